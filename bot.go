@@ -3,6 +3,7 @@
 package tgbotapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,9 +23,14 @@ type HTTPClientI interface {
 
 // BotAPI allows you to interact with the Telegram Bot API.
 type BotAPI struct {
-	config   BotConfigI
-	client   HTTPClientI
-	readonly bool
+	config      BotConfigI
+	client      HTTPClientI
+	readonly    bool
+	retryPolicy *RetryPolicy
+	rateLimiter RateLimiter
+
+	autoMigrateChats bool
+	onChatMigrated   ChatMigrationFunc
 }
 
 // NewBot creates a new BotAPI instance.
@@ -52,7 +58,12 @@ func (b *BotAPI) GetConfig() BotConfigI {
 }
 
 func (b *BotAPI) Validate() error {
-	_, err := b.GetMe()
+	return b.ValidateContext(context.Background())
+}
+
+// ValidateContext is the same as Validate, but uses ctx for the request.
+func (b *BotAPI) ValidateContext(ctx context.Context) error {
+	_, err := b.GetMeContext(ctx)
 	return err
 }
 
@@ -60,6 +71,18 @@ func (bot *BotAPI) SetReadonly(readonly bool) {
 	bot.readonly = readonly
 }
 
+// SetRateLimiter sets the limiter consulted by Request before every call.
+// A nil limiter disables rate limiting, which is the default.
+func (bot *BotAPI) SetRateLimiter(limiter RateLimiter) {
+	bot.rateLimiter = limiter
+}
+
+// SetRetryPolicy enables retrying of failed requests according to policy.
+// A nil policy disables retries, which is the default.
+func (bot *BotAPI) SetRetryPolicy(policy *RetryPolicy) {
+	bot.retryPolicy = policy
+}
+
 func buildParams(in Params) url.Values {
 	if in == nil {
 		return url.Values{}
@@ -76,6 +99,18 @@ func buildParams(in Params) url.Values {
 
 // MakeRequest makes a request to a specific endpoint with our token.
 func (bot *BotAPI) MakeRequest(endpoint string, params Params) (*APIResponse, error) {
+	return bot.MakeRequestContext(context.Background(), endpoint, params)
+}
+
+// MakeRequestContext makes a request to a specific endpoint with our token.
+// The request is aborted when ctx is canceled or its deadline expires.
+func (bot *BotAPI) MakeRequestContext(ctx context.Context, endpoint string, params Params) (*APIResponse, error) {
+	return bot.withRetry(ctx, endpoint, true, func() (*APIResponse, error) {
+		return bot.makeRequest(ctx, endpoint, params)
+	})
+}
+
+func (bot *BotAPI) makeRequest(ctx context.Context, endpoint string, params Params) (*APIResponse, error) {
 	if bot.config.GetDebug() {
 		log.Printf("Endpoint: %s, params: %v\n", endpoint, params)
 	}
@@ -84,7 +119,7 @@ func (bot *BotAPI) MakeRequest(endpoint string, params Params) (*APIResponse, er
 
 	values := buildParams(params)
 
-	req, err := http.NewRequest("POST", method, strings.NewReader(values.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", method, strings.NewReader(values.Encode()))
 	if err != nil {
 		return &APIResponse{}, err
 	}
@@ -149,9 +184,32 @@ func (bot *BotAPI) decodeAPIResponse(responseBody io.Reader, resp *APIResponse)
 
 // UploadFiles makes a request to the API with files.
 func (bot *BotAPI) UploadFiles(endpoint string, params Params, files []RequestFile) (*APIResponse, error) {
+	return bot.UploadFilesContext(context.Background(), endpoint, params, files)
+}
+
+// UploadFilesContext makes a request to the API with files. Canceling ctx
+// aborts the request and stops writing the multipart body.
+//
+// The request is only retried if all files can be read again, so a FileReader
+// is never sent twice.
+func (bot *BotAPI) UploadFilesContext(ctx context.Context, endpoint string, params Params, files []RequestFile) (*APIResponse, error) {
+	return bot.withRetry(ctx, endpoint, filesReplayable(files), func() (*APIResponse, error) {
+		return bot.uploadFiles(ctx, endpoint, params, files)
+	})
+}
+
+func (bot *BotAPI) uploadFiles(ctx context.Context, endpoint string, params Params, files []RequestFile) (*APIResponse, error) {
 	r, w := io.Pipe()
+	defer r.Close()
 	m := multipart.NewWriter(w)
 
+	// Closing the writer unblocks the goroutine below if it is waiting for
+	// the transport to consume the body.
+	stop := context.AfterFunc(ctx, func() {
+		w.CloseWithError(ctx.Err())
+	})
+	defer stop()
+
 	// This code modified from the very helpful @HirbodBehnam
 	// https://github.com/go-telegram-bot-api/telegram-bot-api/issues/354#issuecomment-663856473
 	go func() {
@@ -207,7 +265,7 @@ func (bot *BotAPI) UploadFiles(endpoint string, params Params, files []RequestFi
 
 	method := fmt.Sprintf(bot.config.GetApiEndpoint(), bot.config.GetToken(), endpoint)
 
-	req, err := http.NewRequest("POST", method, r)
+	req, err := http.NewRequestWithContext(ctx, "POST", method, r)
 	if err != nil {
 		return nil, err
 	}
@@ -238,6 +296,7 @@ func (bot *BotAPI) UploadFiles(endpoint string, params Params, files []RequestFi
 		}
 
 		return &apiResp, &Error{
+			Code:               apiResp.ErrorCode,
 			Message:            apiResp.Description,
 			ResponseParameters: parameters,
 		}
@@ -250,7 +309,12 @@ func (bot *BotAPI) UploadFiles(endpoint string, params Params, files []RequestFi
 //
 // It requires the FileID.
 func (bot *BotAPI) GetFileDirectURL(fileID string) (string, error) {
-	file, err := bot.GetFile(FileConfig{fileID})
+	return bot.GetFileDirectURLContext(context.Background(), fileID)
+}
+
+// GetFileDirectURLContext is the same as GetFileDirectURL, but uses ctx for the request.
+func (bot *BotAPI) GetFileDirectURLContext(ctx context.Context, fileID string) (string, error) {
+	file, err := bot.GetFileContext(ctx, FileConfig{fileID})
 
 	if err != nil {
 		return "", err
@@ -265,7 +329,12 @@ func (bot *BotAPI) GetFileDirectURL(fileID string) (string, error) {
 // and so you may get this data from BotAPI.Self without the need for
 // another request.
 func (bot *BotAPI) GetMe() (User, error) {
-	resp, err := bot.MakeRequest("getMe", nil)
+	return bot.GetMeContext(context.Background())
+}
+
+// GetMeContext is the same as GetMe, but uses ctx for the request.
+func (bot *BotAPI) GetMeContext(ctx context.Context) (User, error) {
+	resp, err := bot.MakeRequestContext(ctx, "getMe", nil)
 	if err != nil {
 		return User{}, err
 	}
@@ -295,34 +364,67 @@ func hasFilesNeedingUpload(files []RequestFile) bool {
 
 // Request sends a Chattable to Telegram, and returns the APIResponse.
 func (bot *BotAPI) Request(c Chattable) (*APIResponse, error) {
+	return bot.RequestContext(context.Background(), c)
+}
+
+// RequestContext is the same as Request, but uses ctx for the request.
+func (bot *BotAPI) RequestContext(ctx context.Context, c Chattable) (*APIResponse, error) {
 	params, err := c.params()
 	if err != nil {
 		return nil, err
 	}
 
+	var files []RequestFile
 	if t, ok := c.(Fileable); ok {
-		files := t.files()
+		files = t.files()
+	}
 
-		// If we have files that need to be uploaded, we should delegate the
-		// request to UploadFile.
-		if hasFilesNeedingUpload(files) {
-			return bot.UploadFiles(t.method(), params, files)
-		}
+	resp, err := bot.request(ctx, c.method(), params, files)
+	if bot.migrateChat(err, params) && filesReplayable(files) {
+		return bot.request(ctx, c.method(), params, files)
+	}
+
+	return resp, err
+}
+
+// request sends a request, waiting for the rate limiter before every
+// attempt.
+func (bot *BotAPI) request(ctx context.Context, method string, params Params, files []RequestFile) (*APIResponse, error) {
+	// If we have files that need to be uploaded, we should delegate the
+	// request to uploadFiles.
+	upload := hasFilesNeedingUpload(files)
 
-		// However, if there are no files to be uploaded, there's likely things
-		// that need to be turned into params instead.
+	// However, if there are no files to be uploaded, there's likely things
+	// that need to be turned into params instead.
+	if !upload {
 		for _, file := range files {
 			params[file.Name] = file.Data.SendData()
 		}
 	}
 
-	return bot.MakeRequest(c.method(), params)
+	return bot.withRetry(ctx, method, filesReplayable(files), func() (*APIResponse, error) {
+		if bot.rateLimiter != nil {
+			if err := bot.rateLimiter.Wait(ctx, method, params); err != nil {
+				return nil, err
+			}
+		}
+
+		if upload {
+			return bot.uploadFiles(ctx, method, params, files)
+		}
+		return bot.makeRequest(ctx, method, params)
+	})
 }
 
 // Send will send a Chattable item to Telegram and provides the
 // returned Message.
 func (bot *BotAPI) Send(c Chattable) (Message, error) {
-	resp, err := bot.Request(c)
+	return bot.SendContext(context.Background(), c)
+}
+
+// SendContext is the same as Send, but uses ctx for the request.
+func (bot *BotAPI) SendContext(ctx context.Context, c Chattable) (Message, error) {
+	resp, err := bot.RequestContext(ctx, c)
 	if err != nil {
 		return Message{}, err
 	}
@@ -335,7 +437,12 @@ func (bot *BotAPI) Send(c Chattable) (Message, error) {
 
 // SendMediaGroup sends a media group and returns the resulting messages.
 func (bot *BotAPI) SendMediaGroup(config MediaGroupConfig) ([]Message, error) {
-	resp, err := bot.Request(config)
+	return bot.SendMediaGroupContext(context.Background(), config)
+}
+
+// SendMediaGroupContext is the same as SendMediaGroup, but uses ctx for the request.
+func (bot *BotAPI) SendMediaGroupContext(ctx context.Context, config MediaGroupConfig) ([]Message, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -351,7 +458,12 @@ func (bot *BotAPI) SendMediaGroup(config MediaGroupConfig) ([]Message, error) {
 // It requires UserID.
 // Offset and Limit are optional.
 func (bot *BotAPI) GetUserProfilePhotos(config UserProfilePhotosConfig) (UserProfilePhotos, error) {
-	resp, err := bot.Request(config)
+	return bot.GetUserProfilePhotosContext(context.Background(), config)
+}
+
+// GetUserProfilePhotosContext is the same as GetUserProfilePhotos, but uses ctx for the request.
+func (bot *BotAPI) GetUserProfilePhotosContext(ctx context.Context, config UserProfilePhotosConfig) (UserProfilePhotos, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return UserProfilePhotos{}, err
 	}
@@ -366,7 +478,12 @@ func (bot *BotAPI) GetUserProfilePhotos(config UserProfilePhotosConfig) (UserPro
 //
 // Requires FileID.
 func (bot *BotAPI) GetFile(config FileConfig) (File, error) {
-	resp, err := bot.Request(config)
+	return bot.GetFileContext(context.Background(), config)
+}
+
+// GetFileContext is the same as GetFile, but uses ctx for the request.
+func (bot *BotAPI) GetFileContext(ctx context.Context, config FileConfig) (File, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return File{}, err
 	}
@@ -380,7 +497,12 @@ func (bot *BotAPI) GetFile(config FileConfig) (File, error) {
 // GetWebhookInfo allows you to fetch information about a webhook and if
 // one currently is set, along with pending update count and error messages.
 func (bot *BotAPI) GetWebhookInfo() (WebhookInfo, error) {
-	resp, err := bot.MakeRequest("getWebhookInfo", nil)
+	return bot.GetWebhookInfoContext(context.Background())
+}
+
+// GetWebhookInfoContext is the same as GetWebhookInfo, but uses ctx for the request.
+func (bot *BotAPI) GetWebhookInfoContext(ctx context.Context) (WebhookInfo, error) {
+	resp, err := bot.MakeRequestContext(ctx, "getWebhookInfo", nil)
 	if err != nil {
 		return WebhookInfo{}, err
 	}
@@ -399,7 +521,12 @@ func (bot *BotAPI) GetWebhookInfo() (WebhookInfo, error) {
 // Set Timeout to a large number to reduce requests, so you can get updates
 // instantly instead of having to wait between requests.
 func (bot *BotAPI) GetUpdates(config UpdateConfig) ([]Update, error) {
-	resp, err := bot.Request(config)
+	return bot.GetUpdatesContext(context.Background(), config)
+}
+
+// GetUpdatesContext is the same as GetUpdates, but uses ctx for the request.
+func (bot *BotAPI) GetUpdatesContext(ctx context.Context, config UpdateConfig) ([]Update, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return []Update{}, err
 	}
@@ -423,9 +550,14 @@ func WriteToHTTPResponse(w http.ResponseWriter, c Chattable) error {
 	}
 
 	if t, ok := c.(Fileable); ok {
-		if hasFilesNeedingUpload(t.files()) {
+		files := t.files()
+		if hasFilesNeedingUpload(files) {
 			return errors.New("unable to use http response to upload files")
 		}
+
+		for _, file := range files {
+			params[file.Name] = file.Data.SendData()
+		}
 	}
 
 	values := buildParams(params)
@@ -438,7 +570,12 @@ func WriteToHTTPResponse(w http.ResponseWriter, c Chattable) error {
 
 // GetChat gets information about a chat.
 func (bot *BotAPI) GetChat(config ChatInfoConfig) (ChatFullInfo, error) {
-	resp, err := bot.Request(config)
+	return bot.GetChatContext(context.Background(), config)
+}
+
+// GetChatContext is the same as GetChat, but uses ctx for the request.
+func (bot *BotAPI) GetChatContext(ctx context.Context, config ChatInfoConfig) (ChatFullInfo, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return ChatFullInfo{}, err
 	}
@@ -454,7 +591,12 @@ func (bot *BotAPI) GetChat(config ChatInfoConfig) (ChatFullInfo, error) {
 // If none have been appointed, only the creator will be returned.
 // Bots are not shown, even if they are an administrator.
 func (bot *BotAPI) GetChatAdministrators(config ChatAdministratorsConfig) ([]ChatMember, error) {
-	resp, err := bot.Request(config)
+	return bot.GetChatAdministratorsContext(context.Background(), config)
+}
+
+// GetChatAdministratorsContext is the same as GetChatAdministrators, but uses ctx for the request.
+func (bot *BotAPI) GetChatAdministratorsContext(ctx context.Context, config ChatAdministratorsConfig) ([]ChatMember, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return []ChatMember{}, err
 	}
@@ -467,7 +609,12 @@ func (bot *BotAPI) GetChatAdministrators(config ChatAdministratorsConfig) ([]Cha
 
 // GetChatMembersCount gets the number of users in a chat.
 func (bot *BotAPI) GetChatMembersCount(config ChatMemberCountConfig) (int, error) {
-	resp, err := bot.Request(config)
+	return bot.GetChatMembersCountContext(context.Background(), config)
+}
+
+// GetChatMembersCountContext is the same as GetChatMembersCount, but uses ctx for the request.
+func (bot *BotAPI) GetChatMembersCountContext(ctx context.Context, config ChatMemberCountConfig) (int, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return -1, err
 	}
@@ -480,7 +627,12 @@ func (bot *BotAPI) GetChatMembersCount(config ChatMemberCountConfig) (int, error
 
 // GetChatMember gets a specific chat member.
 func (bot *BotAPI) GetChatMember(config GetChatMemberConfig) (ChatMember, error) {
-	resp, err := bot.Request(config)
+	return bot.GetChatMemberContext(context.Background(), config)
+}
+
+// GetChatMemberContext is the same as GetChatMember, but uses ctx for the request.
+func (bot *BotAPI) GetChatMemberContext(ctx context.Context, config GetChatMemberConfig) (ChatMember, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return ChatMember{}, err
 	}
@@ -493,7 +645,12 @@ func (bot *BotAPI) GetChatMember(config GetChatMemberConfig) (ChatMember, error)
 
 // GetGameHighScores allows you to get the high scores for a game.
 func (bot *BotAPI) GetGameHighScores(config GetGameHighScoresConfig) ([]GameHighScore, error) {
-	resp, err := bot.Request(config)
+	return bot.GetGameHighScoresContext(context.Background(), config)
+}
+
+// GetGameHighScoresContext is the same as GetGameHighScores, but uses ctx for the request.
+func (bot *BotAPI) GetGameHighScoresContext(ctx context.Context, config GetGameHighScoresConfig) ([]GameHighScore, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return []GameHighScore{}, err
 	}
@@ -506,7 +663,12 @@ func (bot *BotAPI) GetGameHighScores(config GetGameHighScoresConfig) ([]GameHigh
 
 // GetInviteLink get InviteLink for a chat
 func (bot *BotAPI) GetInviteLink(config ChatInviteLinkConfig) (string, error) {
-	resp, err := bot.Request(config)
+	return bot.GetInviteLinkContext(context.Background(), config)
+}
+
+// GetInviteLinkContext is the same as GetInviteLink, but uses ctx for the request.
+func (bot *BotAPI) GetInviteLinkContext(ctx context.Context, config ChatInviteLinkConfig) (string, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return "", err
 	}
@@ -519,7 +681,12 @@ func (bot *BotAPI) GetInviteLink(config ChatInviteLinkConfig) (string, error) {
 
 // GetStickerSet returns a StickerSet.
 func (bot *BotAPI) GetStickerSet(config GetStickerSetConfig) (StickerSet, error) {
-	resp, err := bot.Request(config)
+	return bot.GetStickerSetContext(context.Background(), config)
+}
+
+// GetStickerSetContext is the same as GetStickerSet, but uses ctx for the request.
+func (bot *BotAPI) GetStickerSetContext(ctx context.Context, config GetStickerSetConfig) (StickerSet, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return StickerSet{}, err
 	}
@@ -532,7 +699,12 @@ func (bot *BotAPI) GetStickerSet(config GetStickerSetConfig) (StickerSet, error)
 
 // GetCustomEmojiStickers returns a slice of Sticker objects.
 func (bot *BotAPI) GetCustomEmojiStickers(config GetCustomEmojiStickersConfig) ([]Sticker, error) {
-	resp, err := bot.Request(config)
+	return bot.GetCustomEmojiStickersContext(context.Background(), config)
+}
+
+// GetCustomEmojiStickersContext is the same as GetCustomEmojiStickers, but uses ctx for the request.
+func (bot *BotAPI) GetCustomEmojiStickersContext(ctx context.Context, config GetCustomEmojiStickersConfig) ([]Sticker, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return []Sticker{}, err
 	}
@@ -545,7 +717,12 @@ func (bot *BotAPI) GetCustomEmojiStickers(config GetCustomEmojiStickersConfig) (
 
 // StopPoll stops a poll and returns the result.
 func (bot *BotAPI) StopPoll(config StopPollConfig) (Poll, error) {
-	resp, err := bot.Request(config)
+	return bot.StopPollContext(context.Background(), config)
+}
+
+// StopPollContext is the same as StopPoll, but uses ctx for the request.
+func (bot *BotAPI) StopPollContext(ctx context.Context, config StopPollConfig) (Poll, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return Poll{}, err
 	}
@@ -558,12 +735,22 @@ func (bot *BotAPI) StopPoll(config StopPollConfig) (Poll, error) {
 
 // GetMyCommands gets the currently registered commands.
 func (bot *BotAPI) GetMyCommands() ([]BotCommand, error) {
-	return bot.GetMyCommandsWithConfig(GetMyCommandsConfig{})
+	return bot.GetMyCommandsContext(context.Background())
+}
+
+// GetMyCommandsContext is the same as GetMyCommands, but uses ctx for the request.
+func (bot *BotAPI) GetMyCommandsContext(ctx context.Context) ([]BotCommand, error) {
+	return bot.GetMyCommandsWithConfigContext(ctx, GetMyCommandsConfig{})
 }
 
 // GetMyCommandsWithConfig gets the currently registered commands with a config.
 func (bot *BotAPI) GetMyCommandsWithConfig(config GetMyCommandsConfig) ([]BotCommand, error) {
-	resp, err := bot.Request(config)
+	return bot.GetMyCommandsWithConfigContext(context.Background(), config)
+}
+
+// GetMyCommandsWithConfigContext is the same as GetMyCommandsWithConfig, but uses ctx for the request.
+func (bot *BotAPI) GetMyCommandsWithConfigContext(ctx context.Context, config GetMyCommandsConfig) ([]BotCommand, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -578,7 +765,12 @@ func (bot *BotAPI) GetMyCommandsWithConfig(config GetMyCommandsConfig) ([]BotCom
 // forwardMessage, but the copied message doesn't have a link to the original
 // message. Returns the MessageID of the sent message on success.
 func (bot *BotAPI) CopyMessage(config CopyMessageConfig) (MessageID, error) {
-	resp, err := bot.Request(config)
+	return bot.CopyMessageContext(context.Background(), config)
+}
+
+// CopyMessageContext is the same as CopyMessage, but uses ctx for the request.
+func (bot *BotAPI) CopyMessageContext(ctx context.Context, config CopyMessageConfig) (MessageID, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return MessageID{}, err
 	}
@@ -592,9 +784,14 @@ func (bot *BotAPI) CopyMessage(config CopyMessageConfig) (MessageID, error) {
 // AnswerWebAppQuery sets the result of an interaction with a Web App and send a
 // corresponding message on behalf of the user to the chat from which the query originated.
 func (bot *BotAPI) AnswerWebAppQuery(config AnswerWebAppQueryConfig) (SentWebAppMessage, error) {
+	return bot.AnswerWebAppQueryContext(context.Background(), config)
+}
+
+// AnswerWebAppQueryContext is the same as AnswerWebAppQuery, but uses ctx for the request.
+func (bot *BotAPI) AnswerWebAppQueryContext(ctx context.Context, config AnswerWebAppQueryConfig) (SentWebAppMessage, error) {
 	var sentWebAppMessage SentWebAppMessage
 
-	resp, err := bot.Request(config)
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return sentWebAppMessage, err
 	}
@@ -605,9 +802,14 @@ func (bot *BotAPI) AnswerWebAppQuery(config AnswerWebAppQueryConfig) (SentWebApp
 
 // GetMyDefaultAdministratorRights gets the current default administrator rights of the bot.
 func (bot *BotAPI) GetMyDefaultAdministratorRights(config GetMyDefaultAdministratorRightsConfig) (ChatAdministratorRights, error) {
+	return bot.GetMyDefaultAdministratorRightsContext(context.Background(), config)
+}
+
+// GetMyDefaultAdministratorRightsContext is the same as GetMyDefaultAdministratorRights, but uses ctx for the request.
+func (bot *BotAPI) GetMyDefaultAdministratorRightsContext(ctx context.Context, config GetMyDefaultAdministratorRightsConfig) (ChatAdministratorRights, error) {
 	var rights ChatAdministratorRights
 
-	resp, err := bot.Request(config)
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return rights, err
 	}
@@ -645,7 +847,12 @@ func EscapeText(parseMode string, text string) string {
 }
 
 func (bot *BotAPI) SendReaction(config SetMessageReactionConfig) (Message, error) {
-	resp, err := bot.Request(config)
+	return bot.SendReactionContext(context.Background(), config)
+}
+
+// SendReactionContext is the same as SendReaction, but uses ctx for the request.
+func (bot *BotAPI) SendReactionContext(ctx context.Context, config SetMessageReactionConfig) (Message, error) {
+	resp, err := bot.RequestContext(ctx, config)
 	if err != nil {
 		return Message{}, err
 	}
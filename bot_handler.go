@@ -1,128 +1,654 @@
 package tgbotapi
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
 	"time"
 )
 
 type HandlerConfig struct {
-	shutdownChannel chan struct{}
-	bufferSize      int
+	bufferSize int
 }
 
-var defaultConfig = HandlerConfig{
-	shutdownChannel: make(chan struct{}),
-	bufferSize:      100,
+func newHandlerConfig() HandlerConfig {
+	return HandlerConfig{
+		bufferSize: DefaultBufferSize,
+	}
+}
+
+// SetBufferSize sets the capacity of the updates channel. It applies to
+// channels created afterwards. Sizes below 1 are raised to 1, as webhook
+// requests could not be accepted otherwise.
+func (c *HandlerConfig) SetBufferSize(size int) {
+	c.bufferSize = max(size, 1)
 }
 
 type PollingHandler struct {
 	bot *BotAPI
 	HandlerConfig
 	updateConfig UpdateConfig
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	offsetStore OffsetStore
+	commitMu    sync.Mutex
+	committed   int
+	commits     chan struct{}
+	// pending are the IDs of delivered updates that are not committed yet,
+	// in order, and acked are those of them that were committed out of
+	// order.
+	pending []int
+	acked   map[int]bool
+
+	backoff Backoff
+	onError PollingErrorFunc
+	errMu   sync.Mutex
+	err     error
 }
 
+// PollingErrorFunc is called with errors returned by getUpdates. failures is
+// the number of consecutive failures.
+type PollingErrorFunc func(err error, failures int)
+
 func NewPollingHandler(bot *BotAPI, updateConfig UpdateConfig) *PollingHandler {
 	return &PollingHandler{
 		bot:           bot,
-		HandlerConfig: defaultConfig,
+		HandlerConfig: newHandlerConfig(),
 		updateConfig:  updateConfig,
+		committed:     updateConfig.Offset,
+		commits:       make(chan struct{}, 1),
+		acked:         make(map[int]bool),
+		backoff: ExponentialBackoff{
+			BaseDelay: time.Second,
+			MaxDelay:  time.Minute,
+		},
 	}
 }
 
+// SetBackoff sets how long to wait after a failed getUpdates request. It
+// defaults to an ExponentialBackoff from one second up to one minute.
+func (h *PollingHandler) SetBackoff(backoff Backoff) {
+	h.backoff = backoff
+}
+
+// OnError sets the function called with every error returned by getUpdates,
+// instead of logging it.
+func (h *PollingHandler) OnError(fn PollingErrorFunc) {
+	h.onError = fn
+}
+
+// Err returns the error that stopped polling, if any.
+//
+// Polling stops on errors that do not go away by retrying: ErrUnauthorized,
+// when the token is invalid, and ErrConflict, when another getUpdates
+// request is running or a webhook is set.
+func (h *PollingHandler) Err() error {
+	h.errMu.Lock()
+	defer h.errMu.Unlock()
+
+	return h.err
+}
+
+func (h *PollingHandler) setErr(err error) {
+	h.errMu.Lock()
+	defer h.errMu.Unlock()
+
+	h.err = err
+}
+
+func isTerminalPollingError(err error) bool {
+	return errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrConflict)
+}
+
+// SetOffsetStore sets the store the offset is saved to by Commit, and
+// loaded from by Start.
+//
+// With a store, updates are only confirmed to Telegram once they are
+// committed, so updates that were received but not processed are received
+// again after a restart.
+func (h *PollingHandler) SetOffsetStore(store OffsetStore) {
+	h.offsetStore = store
+}
+
+// OverflowPolicy decides what WebhookHandler does with an update when the
+// updates channel is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room in the channel, keeping the
+	// Telegram request open. It is the default.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest update in the channel to make
+	// room for the new one.
+	OverflowDropOldest
+	// OverflowReject responds with 503 Service Unavailable, so Telegram
+	// sends the update again later.
+	OverflowReject
+)
+
+// ErrUpdatesOverflow is returned to Telegram when an update is rejected
+// because the updates channel is full.
+var ErrUpdatesOverflow = errors.New("updates channel is full")
+
+// WebhookHandler receives updates sent by Telegram to a webhook. It
+// implements http.Handler, so it can be mounted on any mux or router, and
+// the received updates are read from Updates.
 type WebhookHandler struct {
 	bot *BotAPI
 	HandlerConfig
+
+	secretToken        string
+	allowedNetworks    []netip.Prefix
+	trustedProxyHeader string
+	overflow           OverflowPolicy
+	dedupStore         DedupStore
+
+	updatesOnce sync.Once
+	updates     chan Update
 }
 
 func NewWebhookHandler(bot *BotAPI) *WebhookHandler {
 	return &WebhookHandler{
 		bot:           bot,
-		HandlerConfig: defaultConfig,
+		HandlerConfig: newHandlerConfig(),
 	}
 }
 
 // InitUpdatesChannel starts and returns a channel for getting updates.
 func (h *PollingHandler) InitUpdatesChannel() (UpdatesChannel, error) {
-	w, err := h.bot.GetWebhookInfo()
+	return h.Start(context.Background())
+}
+
+// Start starts polling for updates and returns the channel they are
+// delivered to. Polling continues until ctx is done, Stop is called or a
+// terminal error occurs, then the channel is closed. See Err.
+//
+// A stopped handler can be started again, and continues after the last
+// delivered update.
+func (h *PollingHandler) Start(ctx context.Context) (UpdatesChannel, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.runningLocked() {
+		return nil, errors.New("polling handler is already running")
+	}
+
+	w, err := h.bot.GetWebhookInfoContext(ctx)
 	if err == nil && w.IsSet() {
 		return nil, errors.New("webhook was set, can't use polling")
 	}
 
+	if h.offsetStore != nil {
+		offset, err := h.offsetStore.LoadOffset(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		h.commitMu.Lock()
+		h.committed = max(h.committed, offset)
+		h.updateConfig.Offset = h.committed
+		// Uncommitted updates are delivered again.
+		h.pending = nil
+		h.commitMu.Unlock()
+	}
+
+	h.setErr(nil)
+
+	pollCtx, cancel := context.WithCancel(ctx)
 	ch := make(chan Update, h.bufferSize)
+	done := make(chan struct{})
+
+	h.cancel = cancel
+	h.done = done
+
+	go h.poll(pollCtx, ch, done)
+
+	return ch, nil
+}
+
+// running reports whether polling is running.
+func (h *PollingHandler) running() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.runningLocked()
+}
+
+// runningLocked reports whether polling is running, and forgets polling that
+// ended because its context was done or of a terminal error. It must be
+// called with h.mu held.
+func (h *PollingHandler) runningLocked() bool {
+	if h.done == nil {
+		return false
+	}
+
+	select {
+	case <-h.done:
+		h.cancel()
+		h.cancel = nil
+		h.done = nil
+		return false
+	default:
+		return true
+	}
+}
+
+func (h *PollingHandler) poll(ctx context.Context, ch chan<- Update, done chan<- struct{}) {
+	defer close(done)
+	defer close(ch)
+
+	failures := 0
+	for ctx.Err() == nil {
+		config := h.updateConfig
+		if h.offsetStore != nil {
+			config.Offset = h.committedOffset()
+		}
+
+		updates, err := h.bot.GetUpdatesContext(ctx, config)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			failures++
+			if h.onError != nil {
+				h.onError(err, failures)
+			}
+
+			if isTerminalPollingError(err) {
+				if h.onError == nil {
+					log.Println("Failed to get updates, stopping:", err)
+				}
+				h.setErr(err)
+				return
+			}
+
+			delay := h.backoff.Delay(failures)
+
+			var apiErr *Error
+			if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+				delay = max(delay, time.Duration(apiErr.RetryAfter)*time.Second)
+			}
+
+			if h.onError == nil {
+				log.Printf("Failed to get updates, retrying in %v: %v\n", delay, err)
+			}
 
-	go func() {
-		for {
 			select {
-			case <-h.shutdownChannel:
-				close(ch)
+			case <-ctx.Done():
 				return
-			default:
+			case <-time.After(delay):
 			}
 
-			updates, err := h.bot.GetUpdates(h.updateConfig)
-			if err != nil {
-				log.Println(err)
-				log.Println("Failed to get updates, retrying in 3 seconds...")
-				time.Sleep(time.Second * 3)
+			continue
+		}
+
+		failures = 0
 
+		delivered := false
+		for _, update := range updates {
+			if update.UpdateID < h.updateConfig.Offset {
 				continue
 			}
 
-			for _, update := range updates {
-				if update.UpdateID >= h.updateConfig.Offset {
-					h.updateConfig.Offset = update.UpdateID + 1
-					ch <- update
-				}
+			h.bot.observeUpdate(&update)
+			h.track(update)
+
+			select {
+			case ch <- update:
+				h.updateConfig.Offset = update.UpdateID + 1
+				delivered = true
+			case <-ctx.Done():
+				return
 			}
 		}
-	}()
 
-	return ch, nil
+		// Uncommitted updates are returned again right away, so wait for
+		// a commit or new updates instead of polling in a loop.
+		if len(updates) > 0 && !delivered {
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.commits:
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func (h *PollingHandler) committedOffset() int {
+	h.commitMu.Lock()
+	defer h.commitMu.Unlock()
+
+	return h.committed
+}
+
+// track records that update is delivered, so the offset is not committed
+// past it before it is.
+func (h *PollingHandler) track(update Update) {
+	if h.offsetStore == nil {
+		return
+	}
+
+	h.commitMu.Lock()
+	defer h.commitMu.Unlock()
+
+	h.pending = append(h.pending, update.UpdateID)
+}
+
+// Commit marks update as processed, and saves the offset following the
+// processed updates to the OffsetStore. Updates may be committed in any
+// order, for example by a WorkerPool: the offset only moves past an update
+// once every update delivered before it was committed too. Without an
+// OffsetStore, Commit does nothing.
+//
+// Run commits every update after it was handled.
+func (h *PollingHandler) Commit(ctx context.Context, update Update) error {
+	if h.offsetStore == nil {
+		return nil
+	}
+
+	h.commitMu.Lock()
+	defer h.commitMu.Unlock()
+
+	if update.UpdateID < h.committed {
+		return nil
+	}
+
+	// An update that was not delivered by the handler moves the offset
+	// only if no delivered update is waiting.
+	offset := update.UpdateID + 1
+	n := 0
+	if len(h.pending) > 0 {
+		h.acked[update.UpdateID] = true
+		for n < len(h.pending) && h.acked[h.pending[n]] {
+			n++
+		}
+		if n == 0 {
+			return nil
+		}
+		offset = h.pending[n-1] + 1
+	}
+
+	if offset <= h.committed {
+		return nil
+	}
+
+	if err := h.offsetStore.SaveOffset(ctx, offset); err != nil {
+		return err
+	}
+	h.committed = offset
+
+	for _, id := range h.pending[:n] {
+		delete(h.acked, id)
+	}
+	h.pending = h.pending[n:]
+
+	select {
+	case h.commits <- struct{}{}:
+	default:
+	}
+
+	return nil
 }
 
-// Stop stops the go routine which receives updates
-func (h *PollingHandler) Stop() {
+// Stop stops the go routine which receives updates. It cancels the
+// in-flight getUpdates request and waits for the routine to exit, or
+// until ctx is done.
+//
+// Afterwards, the updates delivered to the channel are acknowledged to
+// Telegram, so they are not received again by the next getUpdates call.
+func (h *PollingHandler) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.done == nil {
+		return nil
+	}
+
 	if h.bot.GetConfig().GetDebug() {
 		log.Println("Stopping the update receiver routine...")
 	}
-	close(h.shutdownChannel)
+
+	h.cancel()
+
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	h.cancel = nil
+	h.done = nil
+
+	// Acknowledging fails the same way after a terminal error.
+	if h.Err() != nil {
+		return nil
+	}
+
+	return h.acknowledge(ctx)
 }
 
-// ListenForWebhook registers a http handler for a webhook.
-func (h *WebhookHandler) ListenForWebhook(pattern string) UpdatesChannel {
-	ch := make(chan Update, h.bufferSize)
+// acknowledge confirms all delivered updates to Telegram, or with an
+// OffsetStore, all committed updates.
+func (h *PollingHandler) acknowledge(ctx context.Context) error {
+	config := h.updateConfig
+	if h.offsetStore != nil {
+		config.Offset = h.committedOffset()
+	}
+
+	if config.Offset == 0 {
+		return nil
+	}
+
+	config.Limit = 1
+	config.Timeout = 0
+
+	_, err := h.bot.GetUpdatesContext(ctx, config)
+	return err
+}
+
+// Run starts polling and passes every update to handler. It blocks until
+// ctx is done or Stop is called, and the updates already received have been
+// handled and acknowledged. If polling stopped because of a terminal error,
+// it is returned.
+//
+// Handlers of the remaining updates are called with a context that is not
+// canceled together with ctx.
+func (h *PollingHandler) Run(ctx context.Context, handler Handler) error {
+	updates, err := h.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	handlerCtx := context.WithoutCancel(ctx)
+	handleUpdates(handlerCtx, h.bot, handler, updates, h.Commit)
+
+	if err := h.Stop(handlerCtx); err != nil {
+		return err
+	}
+
+	return h.Err()
+}
+
+// handleUpdates passes every update to handler until the channel is closed,
+// and logs the errors. commit, if not nil, is called after every update.
+func handleUpdates(ctx context.Context, bot *BotAPI, handler Handler, updates UpdatesChannel, commit func(ctx context.Context, update Update) error) {
+	for update := range updates {
+		if err := handleUpdate(ctx, handler, bot, update); err != nil {
+			log.Printf("Failed to handle update %d: %v\n", update.UpdateID, err)
+		}
+
+		if commit == nil {
+			continue
+		}
+		if err := commit(ctx, update); err != nil {
+			log.Printf("Failed to commit update %d: %v\n", update.UpdateID, err)
+		}
+	}
+}
+
+// SetOverflowPolicy sets what happens to an update when the updates channel
+// is full.
+func (h *WebhookHandler) SetOverflowPolicy(policy OverflowPolicy) {
+	h.overflow = policy
+}
+
+// SetDedupStore enables deduplication of updates by UpdateID. Updates that
+// Telegram sends again, for example after a timeout, are acknowledged but
+// not delivered again.
+func (h *WebhookHandler) SetDedupStore(store DedupStore) {
+	h.dedupStore = store
+}
+
+// Updates returns the channel the updates received by ServeHTTP are
+// delivered to.
+func (h *WebhookHandler) Updates() UpdatesChannel {
+	return h.channel()
+}
+
+func (h *WebhookHandler) channel() chan Update {
+	h.updatesOnce.Do(func() {
+		h.updates = make(chan Update, h.bufferSize)
+	})
+
+	return h.updates
+}
+
+// ServeHTTP handles a webhook request from Telegram and delivers the update
+// to Updates. It responds as soon as the update is queued, so Telegram does
+// not wait for it to be handled.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	update, ok := h.readUpdate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.deliver(r.Context(), *update); err != nil {
+		h.forget(r.Context(), *update)
+		writeWebhookError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
 
-	http.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
-		update, err := UnmarshalUpdate(r)
+// deliver queues update according to the overflow policy.
+func (h *WebhookHandler) deliver(ctx context.Context, update Update) error {
+	ch := h.channel()
+
+	select {
+	case ch <- update:
+		return nil
+	default:
+	}
+
+	switch h.overflow {
+	case OverflowDropOldest:
+		for {
+			select {
+			case ch <- update:
+				return nil
+			default:
+			}
+
+			select {
+			case dropped := <-ch:
+				log.Printf("Updates channel is full, dropped update %d\n", dropped.UpdateID)
+			default:
+			}
+		}
+	case OverflowReject:
+		return ErrUpdatesOverflow
+	default:
+		select {
+		case ch <- update:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// WebhookReplyFunc handles an update received by a webhook, and returns the
+// request to reply with, or nil to not reply.
+type WebhookReplyFunc func(ctx context.Context, bot *BotAPI, update Update) (Chattable, error)
+
+// ReplyHandler returns a http.Handler that passes every update to fn, and
+// writes the returned request to the webhook response with
+// WriteToHTTPResponse. This saves a round trip per update, but Telegram does
+// not report the result of the request.
+//
+// Requests with files that need to be uploaded can't be sent in the
+// response, and are sent with BotAPI.Request instead.
+//
+// If fn returns an error, the request fails so that Telegram sends the
+// update again, and it is removed from the DedupStore to be accepted then.
+func (h *WebhookHandler) ReplyHandler(fn WebhookReplyFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		update, ok := h.readUpdate(w, r)
+		if !ok {
+			return
+		}
+
+		reply, err := fn(r.Context(), h.bot, *update)
 		if err != nil {
-			errMsg, _ := json.Marshal(map[string]string{"error": err.Error()})
-			w.WriteHeader(http.StatusBadRequest)
-			w.Header().Set("Content-Type", "application/json")
-			_, _ = w.Write(errMsg)
+			log.Printf("Failed to handle update %d: %v\n", update.UpdateID, err)
+			h.forget(r.Context(), *update)
+			writeWebhookError(w, http.StatusInternalServerError, err)
 			return
 		}
 
-		ch <- *update
+		if reply == nil {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if t, ok := reply.(Fileable); ok && hasFilesNeedingUpload(t.files()) {
+			if _, err := h.bot.RequestContext(r.Context(), reply); err != nil {
+				log.Printf("Failed to reply to update %d: %v\n", update.UpdateID, err)
+			}
+
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if err := WriteToHTTPResponse(w, reply); err != nil {
+			log.Printf("Failed to reply to update %d: %v\n", update.UpdateID, err)
+		}
 	})
+}
 
-	return ch
+// ListenForWebhook registers the handler for pattern on
+// http.DefaultServeMux, and returns Updates.
+//
+// To use another mux, register the handler on it directly.
+func (h *WebhookHandler) ListenForWebhook(pattern string) UpdatesChannel {
+	http.Handle(pattern, h)
+
+	return h.Updates()
 }
 
 // ListenForWebhookRespReqFormat registers a http handler for a single incoming webhook.
 func (h *WebhookHandler) ListenForWebhookRespReqFormat(w http.ResponseWriter, r *http.Request) UpdatesChannel {
-	ch := make(chan Update, h.bufferSize)
+	// The update is sent before the channel is returned, so it needs room
+	// for it.
+	ch := make(chan Update, 1)
 
 	func(w http.ResponseWriter, r *http.Request) {
 		defer close(ch)
 
-		update, err := UnmarshalUpdate(r)
-		if err != nil {
-			errMsg, _ := json.Marshal(map[string]string{"error": err.Error()})
-			w.WriteHeader(http.StatusBadRequest)
-			w.Header().Set("Content-Type", "application/json")
-			_, _ = w.Write(errMsg)
+		update, ok := h.readUpdate(w, r)
+		if !ok {
 			return
 		}
 
@@ -132,6 +658,60 @@ func (h *WebhookHandler) ListenForWebhookRespReqFormat(w http.ResponseWriter, r
 	return ch
 }
 
+// readUpdate verifies and parses a webhook request. If it fails, an error
+// response is written and false is returned.
+func (h *WebhookHandler) readUpdate(w http.ResponseWriter, r *http.Request) (*Update, bool) {
+	if err := h.Verify(r); err != nil {
+		status := http.StatusForbidden
+		if errors.Is(err, ErrInvalidSecretToken) {
+			status = http.StatusUnauthorized
+		}
+
+		writeWebhookError(w, status, err)
+		return nil, false
+	}
+
+	update, err := UnmarshalUpdate(r)
+	if err != nil {
+		writeWebhookError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	if h.dedupStore != nil {
+		seen, err := h.dedupStore.MarkSeen(r.Context(), update.UpdateID)
+		if err != nil {
+			log.Printf("Failed to check update %d for duplicates: %v\n", update.UpdateID, err)
+		}
+		if seen {
+			w.WriteHeader(http.StatusOK)
+			return nil, false
+		}
+	}
+
+	h.bot.observeUpdate(update)
+
+	return update, true
+}
+
+// forget removes an update that was not accepted from the DedupStore, so it
+// is accepted when Telegram sends it again.
+func (h *WebhookHandler) forget(ctx context.Context, update Update) {
+	if h.dedupStore == nil {
+		return
+	}
+
+	if err := h.dedupStore.Forget(context.WithoutCancel(ctx), update.UpdateID); err != nil {
+		log.Printf("Failed to forget update %d: %v\n", update.UpdateID, err)
+	}
+}
+
+func writeWebhookError(w http.ResponseWriter, status int, err error) {
+	errMsg, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(errMsg)
+}
+
 // UnmarshalUpdate parses and returns update received via webhook
 func UnmarshalUpdate(r *http.Request) (*Update, error) {
 	if r.Method != http.MethodPost {
@@ -147,3 +727,102 @@ func UnmarshalUpdate(r *http.Request) (*Update, error) {
 
 	return &update, nil
 }
+
+// SecretTokenHeader is the header containing the secret token set with
+// WebhookConfig.SecretToken in every webhook request.
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+var (
+	// ErrInvalidSecretToken is returned when a webhook request does not
+	// contain the expected secret token.
+	ErrInvalidSecretToken = errors.New("invalid secret token")
+	// ErrForbiddenAddress is returned when a webhook request does not come
+	// from an allowed network.
+	ErrForbiddenAddress = errors.New("request from forbidden address")
+)
+
+// TelegramNetworks returns the networks Telegram sends webhook requests from,
+// as published in https://core.telegram.org/bots/webhooks.
+func TelegramNetworks() []netip.Prefix {
+	return []netip.Prefix{
+		netip.MustParsePrefix("149.154.160.0/20"),
+		netip.MustParsePrefix("91.108.4.0/22"),
+	}
+}
+
+// SetSecretToken sets the secret token every webhook request must contain.
+// It should be the same as WebhookConfig.SecretToken. Requests with a
+// different token are rejected with 401 Unauthorized.
+func (h *WebhookHandler) SetSecretToken(token string) {
+	h.secretToken = token
+}
+
+// SetAllowedNetworks restricts webhook requests to the given networks, such
+// as TelegramNetworks(). Requests from other addresses are rejected with
+// 403 Forbidden. Without networks, all addresses are allowed.
+func (h *WebhookHandler) SetAllowedNetworks(networks ...netip.Prefix) {
+	h.allowedNetworks = networks
+}
+
+// SetTrustedProxyHeader sets the header, such as "X-Forwarded-For", that a
+// trusted reverse proxy uses to pass on the client address. The last address
+// in the header is used. Only set it if all requests pass through the proxy,
+// as the header can be forged otherwise.
+func (h *WebhookHandler) SetTrustedProxyHeader(header string) {
+	h.trustedProxyHeader = header
+}
+
+// Verify checks the secret token and the source address of a webhook
+// request. It is done by the handler itself, but can also be used before
+// calling UnmarshalUpdate.
+func (h *WebhookHandler) Verify(r *http.Request) error {
+	if h.secretToken != "" {
+		token := r.Header.Get(SecretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.secretToken)) != 1 {
+			return ErrInvalidSecretToken
+		}
+	}
+
+	if len(h.allowedNetworks) == 0 {
+		return nil
+	}
+
+	addr, err := h.remoteAddr(r)
+	if err != nil {
+		return err
+	}
+
+	for _, network := range h.allowedNetworks {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+
+	return ErrForbiddenAddress
+}
+
+// remoteAddr returns the address of the client that sent r.
+func (h *WebhookHandler) remoteAddr(r *http.Request) (netip.Addr, error) {
+	if h.trustedProxyHeader != "" {
+		if value := r.Header.Get(h.trustedProxyHeader); value != "" {
+			parts := strings.Split(value, ",")
+			addr, err := netip.ParseAddr(strings.TrimSpace(parts[len(parts)-1]))
+			if err != nil {
+				return netip.Addr{}, ErrForbiddenAddress
+			}
+			return addr.Unmap(), nil
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, ErrForbiddenAddress
+	}
+
+	return addr.Unmap(), nil
+}
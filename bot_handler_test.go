@@ -0,0 +1,415 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pollingServer fakes getWebhookInfo, deleteWebhook and getUpdates. It
+// returns every update with an ID of at least the requested offset, and
+// blocks like a long poll when there are none.
+type pollingServer struct {
+	mu      sync.Mutex
+	updates []int
+	offsets []string
+
+	webhookURL string
+	deletes    []string
+}
+
+func (s *pollingServer) Do(req *http.Request) (*http.Response, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	switch path.Base(req.URL.Path) {
+	case "getWebhookInfo":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return newOKResponse(fmt.Sprintf(`{"ok": true, "result": {"url": %q}}`, s.webhookURL)), nil
+	case "deleteWebhook":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.webhookURL = ""
+		s.deletes = append(s.deletes, req.PostForm.Get("drop_pending_updates"))
+		return newOKResponse(`{"ok": true, "result": true}`), nil
+	case "getUpdates":
+	default:
+		return nil, fmt.Errorf("unexpected request to %s", req.URL.Path)
+	}
+
+	s.mu.Lock()
+	s.offsets = append(s.offsets, req.PostForm.Get("offset"))
+	var offset int
+	fmt.Sscan(req.PostForm.Get("offset"), &offset)
+
+	result := "["
+	for _, id := range s.updates {
+		if id >= offset {
+			if len(result) > 1 {
+				result += ","
+			}
+			result += fmt.Sprintf(`{"update_id": %d, "message": {"message_id": 1, "date": 0, "chat": {"id": 1, "type": "private"}}}`, id)
+		}
+	}
+	result += "]"
+	s.mu.Unlock()
+
+	if result == "[]" && req.PostForm.Get("timeout") != "" {
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	return newOKResponse(`{"ok": true, "result": ` + result + `}`), nil
+}
+
+func (s *pollingServer) lastOffset() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.offsets[len(s.offsets)-1]
+}
+
+func TestPollingHandlerRestart(t *testing.T) {
+	server := &pollingServer{updates: []int{10, 11}}
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), server)
+
+	u := NewUpdate(0)
+	u.Timeout = 60
+	h := NewPollingHandler(bot, u)
+
+	ch, err := h.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 10, (<-ch).UpdateID)
+	require.Equal(t, 11, (<-ch).UpdateID)
+
+	_, err = h.Start(context.Background())
+	require.Error(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	require.NoError(t, h.Stop(ctx))
+	require.Equal(t, "12", server.lastOffset())
+
+	_, ok := <-ch
+	require.False(t, ok)
+
+	server.mu.Lock()
+	server.updates = append(server.updates, 12)
+	server.mu.Unlock()
+
+	ch, err = h.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 12, (<-ch).UpdateID)
+	require.NoError(t, h.Stop(ctx))
+}
+
+func TestPollingHandlerRestartAfterCancel(t *testing.T) {
+	server := &pollingServer{updates: []int{1}}
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), server)
+
+	u := NewUpdate(0)
+	u.Timeout = 60
+	h := NewPollingHandler(bot, u)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := h.Start(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, (<-ch).UpdateID)
+
+	cancel()
+	_, ok := <-ch
+	require.False(t, ok)
+
+	server.mu.Lock()
+	server.updates = append(server.updates, 2)
+	server.mu.Unlock()
+
+	ch, err = h.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, (<-ch).UpdateID)
+	require.NoError(t, h.Stop(context.Background()))
+}
+
+func TestPollingHandlerIndependentStop(t *testing.T) {
+	server := &pollingServer{}
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), server)
+
+	u := NewUpdate(0)
+	u.Timeout = 60
+	first := NewPollingHandler(bot, u)
+	second := NewPollingHandler(bot, u)
+
+	firstCh, err := first.Start(context.Background())
+	require.NoError(t, err)
+	secondCh, err := second.Start(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, first.Stop(context.Background()))
+	_, ok := <-firstCh
+	require.False(t, ok)
+
+	select {
+	case <-secondCh:
+		t.Fatal("second handler was stopped")
+	default:
+	}
+	require.NoError(t, second.Stop(context.Background()))
+}
+
+func TestPollingHandlerRun(t *testing.T) {
+	server := &pollingServer{updates: []int{1, 2, 3}}
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), server)
+
+	u := NewUpdate(0)
+	u.Timeout = 60
+	h := NewPollingHandler(bot, u)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var handled []int
+	err := h.Run(ctx, HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		handled = append(handled, update.UpdateID)
+		if update.UpdateID == 3 {
+			cancel()
+		}
+		return nil
+	}))
+
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, handled)
+	require.Equal(t, "4", server.lastOffset())
+}
+
+func newWebhookRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/bot", strings.NewReader(body))
+	req.RemoteAddr = "149.154.167.220:443"
+	return req
+}
+
+func TestWebhookHandlerSecretToken(t *testing.T) {
+	h := NewWebhookHandler(NewBot(NewDefaultBotConfig(TestToken)))
+	h.SetSecretToken("secret")
+
+	rec := httptest.NewRecorder()
+	ch := h.ListenForWebhookRespReqFormat(rec, newWebhookRequest(`{"update_id": 1}`))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	_, ok := <-ch
+	require.False(t, ok)
+
+	req := newWebhookRequest(`{"update_id": 1}`)
+	req.Header.Set(SecretTokenHeader, "secret")
+	rec = httptest.NewRecorder()
+	ch = h.ListenForWebhookRespReqFormat(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 1, (<-ch).UpdateID)
+}
+
+func TestWebhookHandlerAllowedNetworks(t *testing.T) {
+	h := NewWebhookHandler(NewBot(NewDefaultBotConfig(TestToken)))
+	h.SetAllowedNetworks(TelegramNetworks()...)
+
+	require.NoError(t, h.Verify(newWebhookRequest("")))
+
+	req := newWebhookRequest("")
+	req.RemoteAddr = "10.0.0.1:1234"
+	require.ErrorIs(t, h.Verify(req), ErrForbiddenAddress)
+
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 91.108.4.10")
+	require.ErrorIs(t, h.Verify(req), ErrForbiddenAddress)
+
+	h.SetTrustedProxyHeader("X-Forwarded-For")
+	require.NoError(t, h.Verify(req))
+
+	req.Header.Set("X-Forwarded-For", "91.108.4.10, 1.2.3.4")
+	require.ErrorIs(t, h.Verify(req), ErrForbiddenAddress)
+}
+
+func serveWebhook(h http.Handler, updateID int) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newWebhookRequest(fmt.Sprintf(`{"update_id": %d}`, updateID)))
+	return rec.Code
+}
+
+func TestWebhookHandlerServeHTTP(t *testing.T) {
+	h := NewWebhookHandler(NewBot(NewDefaultBotConfig(TestToken)))
+
+	mux := http.NewServeMux()
+	mux.Handle("/bot", h)
+
+	require.Equal(t, http.StatusOK, serveWebhook(mux, 1))
+	require.Equal(t, 1, (<-h.Updates()).UpdateID)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bot", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestWebhookHandlerOverflow(t *testing.T) {
+	newHandler := func(policy OverflowPolicy) *WebhookHandler {
+		h := NewWebhookHandler(NewBot(NewDefaultBotConfig(TestToken)))
+		h.SetBufferSize(1)
+		h.SetOverflowPolicy(policy)
+		return h
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		h := newHandler(OverflowReject)
+		require.Equal(t, http.StatusOK, serveWebhook(h, 1))
+		require.Equal(t, http.StatusServiceUnavailable, serveWebhook(h, 2))
+		require.Equal(t, 1, (<-h.Updates()).UpdateID)
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		h := newHandler(OverflowDropOldest)
+		require.Equal(t, http.StatusOK, serveWebhook(h, 1))
+		require.Equal(t, http.StatusOK, serveWebhook(h, 2))
+		require.Equal(t, 2, (<-h.Updates()).UpdateID)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		h := newHandler(OverflowBlock)
+		require.Equal(t, http.StatusOK, serveWebhook(h, 1))
+
+		code := make(chan int)
+		go func() { code <- serveWebhook(h, 2) }()
+
+		select {
+		case <-code:
+			t.Fatal("request returned while the channel was full")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		require.Equal(t, 1, (<-h.Updates()).UpdateID)
+		require.Equal(t, http.StatusOK, <-code)
+		require.Equal(t, 2, (<-h.Updates()).UpdateID)
+	})
+}
+
+func TestWebhookHandlerZeroBufferSize(t *testing.T) {
+	h := NewWebhookHandler(NewBot(NewDefaultBotConfig(TestToken)))
+	h.SetBufferSize(0)
+	h.SetOverflowPolicy(OverflowReject)
+
+	require.Equal(t, http.StatusOK, serveWebhook(h, 1))
+	require.Equal(t, 1, (<-h.Updates()).UpdateID)
+
+	ch := h.ListenForWebhookRespReqFormat(httptest.NewRecorder(), newWebhookRequest(`{"update_id": 2}`))
+	require.Equal(t, 2, (<-ch).UpdateID)
+}
+
+func TestWebhookHandlerReplyHandler(t *testing.T) {
+	var methods []string
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		methods = append(methods, path.Base(req.URL.Path))
+		_, _ = io.Copy(io.Discard, req.Body)
+		return newErrorResponse(http.StatusOK, `{"ok": true, "result": {}}`), nil
+	})
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+	h := NewWebhookHandler(bot).ReplyHandler(func(ctx context.Context, bot *BotAPI, update Update) (Chattable, error) {
+		switch update.UpdateID {
+		case 1:
+			return NewMessage(ChatID, "pong"), nil
+		case 2:
+			return NewPhoto(ChatID, FileID("photo-id")), nil
+		case 3:
+			return NewPhoto(ChatID, FileBytes{Name: "image.jpg", Bytes: []byte("data")}), nil
+		}
+		return nil, nil
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newWebhookRequest(`{"update_id": 1}`))
+	require.Equal(t, http.StatusOK, rec.Code)
+	values, err := url.ParseQuery(rec.Body.String())
+	require.NoError(t, err)
+	require.Equal(t, "sendMessage", values.Get("method"))
+	require.Equal(t, "pong", values.Get("text"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newWebhookRequest(`{"update_id": 2}`))
+	values, err = url.ParseQuery(rec.Body.String())
+	require.NoError(t, err)
+	require.Equal(t, "sendPhoto", values.Get("method"))
+	require.Equal(t, "photo-id", values.Get("photo"))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newWebhookRequest(`{"update_id": 3}`))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newWebhookRequest(`{"update_id": 4}`))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Body.String())
+
+	require.Equal(t, []string{"sendPhoto"}, methods)
+}
+
+func TestPollingHandlerTerminalError(t *testing.T) {
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		if path.Base(req.URL.Path) == "getWebhookInfo" {
+			return newOKResponse(`{"ok": true, "result": {"url": ""}}`), nil
+		}
+		return newErrorResponse(http.StatusConflict, `{"ok": false, "error_code": 409, "description": "Conflict: terminated by other getUpdates request"}`), nil
+	})
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	var failures []int
+	h := NewPollingHandler(bot, NewUpdate(0))
+	h.OnError(func(err error, n int) {
+		require.ErrorIs(t, err, ErrConflict)
+		failures = append(failures, n)
+	})
+
+	err := h.Run(context.Background(), HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		return nil
+	}))
+	require.ErrorIs(t, err, ErrConflict)
+	require.ErrorIs(t, h.Err(), ErrConflict)
+	require.Equal(t, []int{1}, failures)
+}
+
+func TestPollingHandlerBackoff(t *testing.T) {
+	var calls int
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		if path.Base(req.URL.Path) == "getWebhookInfo" {
+			return newOKResponse(`{"ok": true, "result": {"url": ""}}`), nil
+		}
+
+		calls++
+		if calls <= 2 {
+			return nil, errors.New("connection reset")
+		}
+		return newOKResponse(`{"ok": true, "result": [{"update_id": 1}]}`), nil
+	})
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	var failures []int
+	h := NewPollingHandler(bot, NewUpdate(0))
+	h.SetBackoff(ConstantBackoff(time.Millisecond))
+	h.OnError(func(err error, n int) {
+		failures = append(failures, n)
+	})
+
+	updates, err := h.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, (<-updates).UpdateID)
+	require.Equal(t, []int{1, 2}, failures)
+	require.NoError(t, h.Err())
+}
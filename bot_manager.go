@@ -0,0 +1,304 @@
+package tgbotapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BotManager runs many bots in one process. It serves the webhooks of all
+// bots as one http.Handler, and passes the updates of every bot to its own
+// Handler.
+//
+// Every bot is served under a path ending with its ID, for example
+// "/telegram/123456" when the manager is mounted on "/telegram/". Its secret
+// token is derived from the bot token, so it is the same across restarts and
+// replicas.
+type BotManager struct {
+	client      HTTPClientI
+	apiEndpoint string
+	rateLimiter RateLimiter
+
+	mu   sync.RWMutex
+	bots map[int64]*managedBot
+}
+
+type managedBot struct {
+	id      int64
+	bot     *BotAPI
+	handler Handler
+	webhook *WebhookHandler
+	cancel  context.CancelFunc
+	done    chan struct{}
+	// requests are the webhook requests in progress.
+	requests sync.WaitGroup
+}
+
+// NewBotManager creates a BotManager whose bots send requests with client.
+func NewBotManager(client HTTPClientI) *BotManager {
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &BotManager{
+		client:      client,
+		apiEndpoint: APIEndpoint,
+		bots:        make(map[int64]*managedBot),
+	}
+}
+
+// SetAPIEndpoint sets the API endpoint of bots added afterwards.
+func (m *BotManager) SetAPIEndpoint(endpoint string) {
+	m.apiEndpoint = endpoint
+}
+
+// SetRateLimiter sets the rate limiter shared by bots added afterwards.
+//
+// Telegram applies its limits to every bot separately, so a limiter shared
+// by many bots is more restrictive than needed. It is useful to cap the
+// requests of the whole process.
+func (m *BotManager) SetRateLimiter(limiter RateLimiter) {
+	m.rateLimiter = limiter
+}
+
+// BotID returns the ID of the bot with the given token, which is the part
+// before the colon.
+func BotID(token string) (int64, error) {
+	id, _, ok := strings.Cut(token, ":")
+	if !ok {
+		return 0, errors.New("invalid bot token")
+	}
+
+	return strconv.ParseInt(id, 10, 64)
+}
+
+// AddBot adds the bot with the given token, passing its updates to handler.
+func (m *BotManager) AddBot(token string, handler Handler) (*BotAPI, error) {
+	id, err := BotID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	bot := NewBotWithClient(NewBotConfig(token, m.apiEndpoint, false), m.client)
+	bot.SetRateLimiter(m.rateLimiter)
+
+	webhook := NewWebhookHandler(bot)
+	webhook.SetSecretToken(webhookSecretToken(token))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.bots[id]; ok {
+		return nil, fmt.Errorf("bot %d was already added", id)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &managedBot{
+		id:      id,
+		bot:     bot,
+		handler: handler,
+		webhook: webhook,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	m.bots[id] = b
+
+	go b.serve(ctx)
+
+	return bot, nil
+}
+
+// webhookSecretToken derives the secret token of a bot's webhook from its
+// token.
+func webhookSecretToken(token string) string {
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte("webhook secret token"))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// serve passes updates to the bot's handler until ctx is done and the
+// webhook requests in progress are finished, then handles the updates that
+// were already received.
+func (b *managedBot) serve(ctx context.Context) {
+	defer close(b.done)
+
+	updates := b.webhook.Updates()
+	handlerCtx := context.WithoutCancel(ctx)
+
+	// Requests in progress may be waiting for room in the updates channel,
+	// so updates are still handled until they are finished.
+	idle := make(chan struct{})
+	context.AfterFunc(ctx, func() {
+		b.requests.Wait()
+		close(idle)
+	})
+
+	for {
+		select {
+		case update := <-updates:
+			b.handle(handlerCtx, update)
+		case <-idle:
+			for {
+				select {
+				case update := <-updates:
+					b.handle(handlerCtx, update)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *managedBot) handle(ctx context.Context, update Update) {
+	if err := handleUpdate(ctx, b.handler, b.bot, update); err != nil {
+		log.Printf("Bot %d failed to handle update %d: %v\n", b.id, update.UpdateID, err)
+	}
+}
+
+// RemoveBot removes the bot with the given ID. Its webhook is no longer
+// served, and it returns once the webhook requests in progress are finished
+// and the updates already received are handled, or ctx is done.
+func (m *BotManager) RemoveBot(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	b, ok := m.bots[id]
+	delete(m.bots, id)
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("bot %d not found", id)
+	}
+
+	b.cancel()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close removes all bots.
+func (m *BotManager) Close(ctx context.Context) error {
+	var errs []error
+	for _, id := range m.BotIDs() {
+		errs = append(errs, m.RemoveBot(ctx, id))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Bot returns the bot with the given ID.
+func (m *BotManager) Bot(id int64) (*BotAPI, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, ok := m.bots[id]
+	if !ok {
+		return nil, false
+	}
+
+	return b.bot, true
+}
+
+// BotByToken returns the bot with the given token.
+func (m *BotManager) BotByToken(token string) (*BotAPI, bool) {
+	id, err := BotID(token)
+	if err != nil {
+		return nil, false
+	}
+
+	bot, ok := m.Bot(id)
+	if !ok || bot.config.GetToken() != token {
+		return nil, false
+	}
+
+	return bot, true
+}
+
+// BotIDs returns the IDs of all bots, in ascending order.
+func (m *BotManager) BotIDs() []int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]int64, 0, len(m.bots))
+	for id := range m.bots {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
+
+// WebhookHandler returns the WebhookHandler of the bot with the given ID, to
+// configure it further.
+func (m *BotManager) WebhookHandler(id int64) (*WebhookHandler, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, ok := m.bots[id]
+	if !ok {
+		return nil, false
+	}
+
+	return b.webhook, true
+}
+
+// EnsureWebhook sets the webhook of the bot with the given ID to its path
+// under baseURL, such as "https://example.com/telegram", if it is not set
+// already. See BotAPI.EnsureWebhook.
+//
+// The secret token is not compared, so after changing it with
+// WebhookHandler, the webhook must be set with setWebhook directly.
+func (m *BotManager) EnsureWebhook(ctx context.Context, id int64, baseURL string) (WebhookHealth, error) {
+	webhook, ok := m.WebhookHandler(id)
+	if !ok {
+		return WebhookHealth{}, fmt.Errorf("bot %d not found", id)
+	}
+
+	config, err := NewWebhook(strings.TrimSuffix(baseURL, "/") + "/" + strconv.FormatInt(id, 10))
+	if err != nil {
+		return WebhookHealth{}, err
+	}
+	config.SecretToken = webhook.secretToken
+
+	return webhook.bot.EnsureWebhookContext(ctx, config)
+}
+
+// ServeHTTP passes a webhook request to the bot whose ID is the last element
+// of the path.
+func (m *BotManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(path.Base(r.URL.Path), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	m.mu.RLock()
+	b, ok := m.bots[id]
+	if ok {
+		// RemoveBot deletes the bot under the lock before waiting for its
+		// requests, so none is added after.
+		b.requests.Add(1)
+	}
+	m.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	defer b.requests.Done()
+
+	b.webhook.ServeHTTP(w, r)
+}
@@ -0,0 +1,163 @@
+package tgbotapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBotID(t *testing.T) {
+	id, err := BotID("123456:ABC-DEF")
+	require.NoError(t, err)
+	require.Equal(t, int64(123456), id)
+
+	_, err = BotID("invalid")
+	require.Error(t, err)
+}
+
+func TestBotManager(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		handled = make(map[int64][]int)
+		done    = make(chan struct{}, 2)
+	)
+
+	handler := HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		id, _ := BotID(bot.GetConfig().GetToken())
+
+		mu.Lock()
+		handled[id] = append(handled[id], update.UpdateID)
+		mu.Unlock()
+
+		done <- struct{}{}
+		return nil
+	})
+
+	m := NewBotManager(nil)
+	_, err := m.AddBot("1:first", handler)
+	require.NoError(t, err)
+	_, err = m.AddBot("2:second", handler)
+	require.NoError(t, err)
+	_, err = m.AddBot("2:second", handler)
+	require.Error(t, err)
+
+	require.Equal(t, []int64{1, 2}, m.BotIDs())
+	_, ok := m.BotByToken("2:wrong")
+	require.False(t, ok)
+
+	mux := http.NewServeMux()
+	mux.Handle("/telegram/", m)
+
+	post := func(id int64, token string, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/telegram/"+strconv.FormatInt(id, 10), strings.NewReader(body))
+		req.Header.Set(SecretTokenHeader, webhookSecretToken(token))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusOK, post(1, "1:first", `{"update_id": 10}`))
+	require.Equal(t, http.StatusOK, post(2, "2:second", `{"update_id": 20}`))
+	<-done
+	<-done
+
+	// The secret token of another bot is rejected.
+	require.Equal(t, http.StatusUnauthorized, post(1, "2:second", `{"update_id": 11}`))
+
+	require.NoError(t, m.RemoveBot(context.Background(), 1))
+	require.Equal(t, http.StatusNotFound, post(1, "1:first", `{"update_id": 12}`))
+
+	mu.Lock()
+	require.Equal(t, map[int64][]int{1: {10}, 2: {20}}, handled)
+	mu.Unlock()
+
+	require.NoError(t, m.Close(context.Background()))
+	require.Empty(t, m.BotIDs())
+}
+
+func TestBotManagerEnsureWebhook(t *testing.T) {
+	var params []string
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		if path.Base(req.URL.Path) == "setWebhook" {
+			require.NoError(t, req.ParseForm())
+			params = append(params, req.PostForm.Get("url"), req.PostForm.Get("secret_token"))
+		}
+		_, _ = io.Copy(io.Discard, req.Body)
+
+		if path.Base(req.URL.Path) == "getWebhookInfo" {
+			return newOKResponse(`{"ok": true, "result": {"url": ""}}`), nil
+		}
+		return newOKResponse(`{"ok": true, "result": true}`), nil
+	})
+
+	m := NewBotManager(client)
+	_, err := m.AddBot("1:first", HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		return nil
+	}))
+	require.NoError(t, err)
+
+	_, err = m.EnsureWebhook(context.Background(), 1, "https://example.com/telegram/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://example.com/telegram/1", webhookSecretToken("1:first")}, params)
+}
+
+// signalReader closes started when it is first read.
+type signalReader struct {
+	io.Reader
+	once    sync.Once
+	started chan struct{}
+}
+
+func (r *signalReader) Read(p []byte) (int, error) {
+	r.once.Do(func() { close(r.started) })
+	return r.Reader.Read(p)
+}
+
+func TestBotManagerRemoveBotInFlight(t *testing.T) {
+	handled := make(chan int, 1)
+	m := NewBotManager(nil)
+	_, err := m.AddBot("1:first", HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		handled <- update.UpdateID
+		return nil
+	}))
+	require.NoError(t, err)
+
+	pr, pw := io.Pipe()
+	body := &signalReader{Reader: pr, started: make(chan struct{})}
+	req := httptest.NewRequest(http.MethodPost, "/telegram/1", body)
+	req.Header.Set(SecretTokenHeader, webhookSecretToken("1:first"))
+	rec := httptest.NewRecorder()
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		m.ServeHTTP(rec, req)
+	}()
+	<-body.started
+
+	removed := make(chan error)
+	go func() { removed <- m.RemoveBot(context.Background(), 1) }()
+
+	select {
+	case <-removed:
+		t.Fatal("bot was removed while a request was in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pw.Write([]byte(`{"update_id": 10}`))
+	pw.Close()
+	<-served
+
+	require.NoError(t, <-removed)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 10, <-handled)
+}
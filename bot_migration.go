@@ -0,0 +1,63 @@
+package tgbotapi
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ChatMigrationFunc is called when a group has been upgraded to a supergroup
+// and its chat ID changed from oldChatID to newChatID.
+type ChatMigrationFunc func(oldChatID, newChatID int64)
+
+// SetAutoMigrateChats enables re-sending requests to the new chat ID when
+// Telegram reports that the target group was migrated to a supergroup.
+func (bot *BotAPI) SetAutoMigrateChats(enabled bool) {
+	bot.autoMigrateChats = enabled
+}
+
+// SetChatMigrationHandler sets a function that is called whenever a chat
+// migration is noticed, either from an API error or from an incoming message
+// with MigrateToChatID set. Use it to update stored chat IDs.
+func (bot *BotAPI) SetChatMigrationHandler(fn ChatMigrationFunc) {
+	bot.onChatMigrated = fn
+}
+
+// migrateChat reports a migration contained in err and rewrites the chat_id
+// in params. It returns true if the request should be sent again.
+func (bot *BotAPI) migrateChat(err error, params Params) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.MigrateToChatID == 0 {
+		return false
+	}
+
+	oldChatID, parseErr := strconv.ParseInt(params["chat_id"], 10, 64)
+	if parseErr != nil {
+		return false
+	}
+
+	bot.chatMigrated(oldChatID, apiErr.MigrateToChatID)
+
+	if !bot.autoMigrateChats {
+		return false
+	}
+
+	params["chat_id"] = strconv.FormatInt(apiErr.MigrateToChatID, 10)
+	return true
+}
+
+func (bot *BotAPI) chatMigrated(oldChatID, newChatID int64) {
+	if bot.config.GetDebug() {
+		log.Printf("Chat %d migrated to %d\n", oldChatID, newChatID)
+	}
+
+	if bot.onChatMigrated != nil {
+		bot.onChatMigrated(oldChatID, newChatID)
+	}
+}
+
+// observeUpdate reports chat migrations announced by incoming updates.
+func (bot *BotAPI) observeUpdate(update *Update) {
+	if update.Message != nil && update.Message.MigrateToChatID != 0 {
+		bot.chatMigrated(update.Message.Chat.ID, update.Message.MigrateToChatID)
+	}
+}
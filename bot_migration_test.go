@@ -0,0 +1,50 @@
+package tgbotapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoMigrateChats(t *testing.T) {
+	var chatIDs []string
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		require.NoError(t, req.ParseForm())
+		chatIDs = append(chatIDs, req.PostForm.Get("chat_id"))
+
+		if len(chatIDs) == 1 {
+			return newErrorResponse(http.StatusBadRequest, `{"ok": false, "error_code": 400, "description": "Bad Request: group chat was upgraded to a supergroup chat", "parameters": {"migrate_to_chat_id": -1001234}}`), nil
+		}
+		return newOKResponse(`{"ok": true, "result": {"message_id": 1, "date": 0, "chat": {"id": -1001234, "type": "supergroup"}}}`), nil
+	})
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+	bot.SetAutoMigrateChats(true)
+
+	var migrated [2]int64
+	bot.SetChatMigrationHandler(func(oldChatID, newChatID int64) {
+		migrated = [2]int64{oldChatID, newChatID}
+	})
+
+	msg, err := bot.Send(NewMessage(-1234, "test"))
+	require.NoError(t, err)
+	require.Equal(t, int64(-1001234), msg.Chat.ID)
+	require.Equal(t, []string{"-1234", "-1001234"}, chatIDs)
+	require.Equal(t, [2]int64{-1234, -1001234}, migrated)
+}
+
+func TestObserveUpdateMigration(t *testing.T) {
+	bot := NewBot(NewDefaultBotConfig(TestToken))
+
+	var migrated [2]int64
+	bot.SetChatMigrationHandler(func(oldChatID, newChatID int64) {
+		migrated = [2]int64{oldChatID, newChatID}
+	})
+
+	bot.observeUpdate(&Update{Message: &Message{
+		Chat:            Chat{ID: -1234},
+		MigrateToChatID: -1001234,
+	}})
+	require.Equal(t, [2]int64{-1234, -1001234}, migrated)
+}
@@ -0,0 +1,216 @@
+package tgbotapi
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter throttles outgoing requests before they are sent.
+type RateLimiter interface {
+	// Wait blocks until a request to method with params may be sent.
+	// It returns an error if ctx is done first.
+	Wait(ctx context.Context, method string, params Params) error
+}
+
+// Limit allows Events requests per Interval, in bursts of up to Events.
+// A zero Limit is unlimited.
+type Limit struct {
+	Events   int
+	Interval time.Duration
+}
+
+// RateLimits are the limits used by ChatRateLimiter.
+type RateLimits struct {
+	// Global is shared by all chats.
+	Global Limit
+	// PrivateChat applies to every private chat.
+	PrivateChat Limit
+	// GroupChat applies to every group, supergroup and channel.
+	GroupChat Limit
+	// Limited reports whether requests to method count against the limits.
+	// If nil, only methods that send messages are limited, so methods such as
+	// answerCallbackQuery are never delayed.
+	Limited func(method string) bool
+}
+
+// DefaultRateLimits returns the limits documented by Telegram: about 30
+// messages per second overall, one message per second in a private chat and
+// 20 messages per minute in a group.
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		Global:      Limit{Events: 30, Interval: time.Second},
+		PrivateChat: Limit{Events: 1, Interval: time.Second},
+		GroupChat:   Limit{Events: 20, Interval: time.Minute},
+	}
+}
+
+// isMessageMethod returns true for methods that send a message to a chat.
+func isMessageMethod(method string) bool {
+	switch {
+	case method == "sendChatAction":
+		return false
+	case strings.HasPrefix(method, "send"),
+		strings.HasPrefix(method, "forwardMessage"),
+		strings.HasPrefix(method, "copyMessage"):
+		return true
+	default:
+		return false
+	}
+}
+
+// tokenBucket is a token bucket holding up to limit.Events tokens.
+type tokenBucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit Limit, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		limit:  limit,
+		tokens: float64(limit.Events),
+		last:   now,
+	}
+}
+
+func (b *tokenBucket) unlimited() bool {
+	return b == nil || b.limit.Events <= 0 || b.limit.Interval <= 0
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last)
+	b.last = now
+	b.tokens += float64(b.limit.Events) * float64(elapsed) / float64(b.limit.Interval)
+	if b.tokens > float64(b.limit.Events) {
+		b.tokens = float64(b.limit.Events)
+	}
+}
+
+// wait returns how long it takes until a token is available.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	if b.unlimited() {
+		return 0
+	}
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	missing := 1 - b.tokens
+	return time.Duration(missing * float64(b.limit.Interval) / float64(b.limit.Events))
+}
+
+func (b *tokenBucket) take() {
+	if !b.unlimited() {
+		b.tokens--
+	}
+}
+
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Events)
+}
+
+// chatBucketsSweepSize is the number of chat buckets after which idle
+// buckets are first removed.
+const chatBucketsSweepSize = 1024
+
+// ChatRateLimiter is a RateLimiter which applies a global limit and a limit
+// per chat, keyed on the chat_id parameter of a request.
+type ChatRateLimiter struct {
+	limits RateLimits
+
+	mu     sync.Mutex
+	global *tokenBucket
+	chats  map[string]*tokenBucket
+	// sweepAt is the number of chat buckets at which idle buckets are
+	// removed next.
+	sweepAt int
+}
+
+// NewChatRateLimiter creates a new ChatRateLimiter with the given limits.
+func NewChatRateLimiter(limits RateLimits) *ChatRateLimiter {
+	return &ChatRateLimiter{
+		limits:  limits,
+		global:  newTokenBucket(limits.Global, time.Now()),
+		chats:   make(map[string]*tokenBucket),
+		sweepAt: chatBucketsSweepSize,
+	}
+}
+
+// Wait blocks until the global limit and the limit of the target chat allow
+// another request.
+func (l *ChatRateLimiter) Wait(ctx context.Context, method string, params Params) error {
+	limited := l.limits.Limited
+	if limited == nil {
+		limited = isMessageMethod
+	}
+	if !limited(method) {
+		return nil
+	}
+
+	chatID := params["chat_id"]
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		chat := l.chatBucket(chatID, now)
+
+		delay := l.global.wait(now)
+		if d := chat.wait(now); d > delay {
+			delay = d
+		}
+
+		if delay == 0 {
+			l.global.take()
+			chat.take()
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// chatBucket returns the bucket for chatID. It must be called with l.mu held.
+func (l *ChatRateLimiter) chatBucket(chatID string, now time.Time) *tokenBucket {
+	if chatID == "" {
+		return nil
+	}
+
+	if bucket, ok := l.chats[chatID]; ok {
+		return bucket
+	}
+
+	if len(l.chats) >= l.sweepAt {
+		for id, bucket := range l.chats {
+			if bucket.full(now) {
+				delete(l.chats, id)
+			}
+		}
+
+		// Sweep again once the busy buckets have doubled, so lookups stay
+		// amortized O(1).
+		l.sweepAt = max(2*len(l.chats), chatBucketsSweepSize)
+	}
+
+	limit := l.limits.GroupChat
+	if id, err := strconv.ParseInt(chatID, 10, 64); err == nil && id > 0 {
+		limit = l.limits.PrivateChat
+	}
+
+	bucket := newTokenBucket(limit, now)
+	l.chats[chatID] = bucket
+	return bucket
+}
@@ -0,0 +1,92 @@
+package tgbotapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatRateLimiterPerChat(t *testing.T) {
+	limiter := NewChatRateLimiter(RateLimits{
+		Global:      Limit{Events: 100, Interval: time.Second},
+		PrivateChat: Limit{Events: 1, Interval: 50 * time.Millisecond},
+		GroupChat:   Limit{Events: 2, Interval: time.Minute},
+	})
+	ctx := context.Background()
+
+	start := time.Now()
+	require.NoError(t, limiter.Wait(ctx, "sendMessage", Params{"chat_id": "1"}))
+	require.NoError(t, limiter.Wait(ctx, "sendMessage", Params{"chat_id": "2"}))
+	require.NoError(t, limiter.Wait(ctx, "sendMessage", Params{"chat_id": "1"}))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	require.NoError(t, limiter.Wait(ctx, "sendMessage", Params{"chat_id": "-100"}))
+	require.NoError(t, limiter.Wait(ctx, "sendMessage", Params{"chat_id": "-100"}))
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err := limiter.Wait(timeout, "sendPhoto", Params{"chat_id": "-100"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestChatRateLimiterSweep(t *testing.T) {
+	limiter := NewChatRateLimiter(RateLimits{
+		PrivateChat: Limit{Events: 1, Interval: time.Hour},
+	})
+	ctx := context.Background()
+
+	// Busy buckets survive the sweep, which then waits for the map to
+	// double instead of scanning it for every new chat.
+	for i := 1; i <= chatBucketsSweepSize+10; i++ {
+		require.NoError(t, limiter.Wait(ctx, "sendMessage", Params{"chat_id": strconv.Itoa(i)}))
+	}
+	require.Len(t, limiter.chats, chatBucketsSweepSize+10)
+	require.Equal(t, 2*chatBucketsSweepSize, limiter.sweepAt)
+}
+
+func TestChatRateLimiterBypass(t *testing.T) {
+	limiter := NewChatRateLimiter(RateLimits{
+		Global: Limit{Events: 1, Interval: time.Hour},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, limiter.Wait(ctx, "sendMessage", Params{"chat_id": "1"}))
+	require.NoError(t, limiter.Wait(ctx, "answerCallbackQuery", Params{}))
+	require.NoError(t, limiter.Wait(ctx, "sendChatAction", Params{"chat_id": "1"}))
+	require.ErrorIs(t, limiter.Wait(ctx, "sendMessage", Params{"chat_id": "2"}), context.DeadlineExceeded)
+}
+
+type recordingLimiter struct {
+	chatIDs []string
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context, method string, params Params) error {
+	l.chatIDs = append(l.chatIDs, params["chat_id"])
+	return nil
+}
+
+func TestRateLimiterWaitsForEveryAttempt(t *testing.T) {
+	var calls int
+	client := countingClient(&calls,
+		newErrorResponse(http.StatusBadGateway, `{"ok": false, "error_code": 502, "description": "Bad Gateway"}`),
+		newErrorResponse(http.StatusBadRequest, `{"ok": false, "error_code": 400, "description": "Bad Request: group chat was upgraded to a supergroup chat", "parameters": {"migrate_to_chat_id": -1001234}}`),
+		newOKResponse(`{"ok": true, "result": {"message_id": 1, "date": 0, "chat": {"id": -1001234, "type": "supergroup"}}}`),
+	)
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+	bot.SetRetryPolicy(testRetryPolicy("sendMessage"))
+	bot.SetAutoMigrateChats(true)
+
+	limiter := &recordingLimiter{}
+	bot.SetRateLimiter(limiter)
+
+	_, err := bot.Send(NewMessage(-1234, "test"))
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+	require.Equal(t, []string{"-1234", "-1234", "-1001234"}, limiter.chatIDs)
+}
@@ -0,0 +1,208 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// RetryPolicy describes how failed requests are retried.
+//
+// Flood control errors are retried after the delay Telegram asks for in
+// ResponseParameters.RetryAfter. Server errors and transport errors are
+// retried with exponential backoff and jitter. Other errors are returned
+// immediately.
+//
+// Only idempotent methods, such as the get* family, and methods listed in
+// Methods are ever replayed.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is the backoff delay before the first retry. It doubles with
+	// every following attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay. If Telegram asks to wait longer than
+	// MaxDelay, the error is returned instead of waiting.
+	MaxDelay time.Duration
+	// Methods lists additional API methods that are safe to replay,
+	// for example "sendMessage".
+	Methods []string
+}
+
+// NewRetryPolicy creates a RetryPolicy with default delays that makes at most
+// maxAttempts attempts.
+func NewRetryPolicy(maxAttempts int, methods ...string) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Methods:     methods,
+	}
+}
+
+// idempotentMethods are methods without a get prefix that can be safely
+// repeated.
+var idempotentMethods = map[string]bool{
+	"sendChatAction":                  true,
+	"setWebhook":                      true,
+	"deleteWebhook":                   true,
+	"setMyCommands":                   true,
+	"deleteMyCommands":                true,
+	"setMyName":                       true,
+	"setMyDescription":                true,
+	"setMyShortDescription":           true,
+	"setChatMenuButton":               true,
+	"setMyDefaultAdministratorRights": true,
+}
+
+func (p *RetryPolicy) allows(method string) bool {
+	if strings.HasPrefix(method, "get") || idempotentMethods[method] {
+		return true
+	}
+
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delay returns how long to wait before the next attempt, or false if err
+// should not be retried.
+func (p *RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.RetryAfter > 0:
+			d := time.Duration(apiErr.RetryAfter) * time.Second
+			if p.MaxDelay > 0 && d > p.MaxDelay {
+				return 0, false
+			}
+			return d, true
+		case apiErr.Code >= 500:
+			return p.backoff(attempt), true
+		default:
+			return 0, false
+		}
+	}
+
+	return p.backoff(attempt), true
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	return exponentialBackoff(p.BaseDelay, p.MaxDelay, attempt)
+}
+
+// exponentialBackoff returns base doubled for every attempt after the first,
+// capped at max, with jitter. Without a cap, the delay saturates instead of
+// overflowing.
+func exponentialBackoff(base, max time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	d := base
+	for i := 1; i < attempt && (max <= 0 || d < max); i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64
+			break
+		}
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+
+	// Wait at least half of the delay, the rest is random.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// Backoff decides how long polling waits after failed getUpdates requests.
+type Backoff interface {
+	// Delay returns how long to wait after the given number of consecutive
+	// failures, starting at 1.
+	Delay(failures int) time.Duration
+}
+
+// ConstantBackoff waits the same time after every failure.
+type ConstantBackoff time.Duration
+
+// Delay returns b.
+func (b ConstantBackoff) Delay(failures int) time.Duration {
+	return time.Duration(b)
+}
+
+// ExponentialBackoff doubles the delay after every consecutive failure, with
+// jitter.
+type ExponentialBackoff struct {
+	// BaseDelay is the delay after the first failure.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay.
+	MaxDelay time.Duration
+}
+
+// Delay returns the delay after failures consecutive failures.
+func (b ExponentialBackoff) Delay(failures int) time.Duration {
+	return exponentialBackoff(b.BaseDelay, b.MaxDelay, failures)
+}
+
+// withRetry calls attempt until it succeeds or the bot's retry policy gives
+// up. Requests that are not replayable are only attempted once.
+func (bot *BotAPI) withRetry(ctx context.Context, endpoint string, replayable bool, attempt func() (*APIResponse, error)) (*APIResponse, error) {
+	policy := bot.retryPolicy
+	if policy == nil || !replayable || !policy.allows(endpoint) {
+		return attempt()
+	}
+
+	for i := 1; ; i++ {
+		resp, err := attempt()
+		if err == nil || i >= policy.MaxAttempts {
+			return resp, err
+		}
+
+		delay, ok := policy.delay(i, err)
+		if !ok {
+			return resp, err
+		}
+
+		if bot.config.GetDebug() {
+			log.Printf("Endpoint: %s, attempt %d failed: %v, retrying in %s\n", endpoint, i, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// filesReplayable returns true if every file that needs to be uploaded can be
+// read again for another attempt.
+func filesReplayable(files []RequestFile) bool {
+	for _, file := range files {
+		if !file.Data.NeedsUpload() {
+			continue
+		}
+
+		switch file.Data.(type) {
+		case FileBytes, FilePath:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,133 @@
+package tgbotapi
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newErrorResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Header:     make(http.Header),
+	}
+}
+
+func countingClient(calls *int, responses ...*http.Response) HTTPClientI {
+	return httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			_, _ = io.Copy(io.Discard, req.Body)
+		}
+		resp := responses[*calls]
+		*calls++
+		return resp, nil
+	})
+}
+
+func testRetryPolicy(methods ...string) *RetryPolicy {
+	policy := NewRetryPolicy(3, methods...)
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetryServerError(t *testing.T) {
+	var calls int
+	client := countingClient(&calls,
+		newErrorResponse(http.StatusBadGateway, `{"ok": false, "error_code": 502, "description": "Bad Gateway"}`),
+		newOKResponse(`{"ok": true, "result": {"id": 123456789, "is_bot": true, "first_name": "MyBot"}}`),
+	)
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+	bot.SetRetryPolicy(testRetryPolicy())
+
+	_, err := bot.GetMe()
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+}
+
+func TestRetrySkipsNonIdempotentMethods(t *testing.T) {
+	var calls int
+	client := countingClient(&calls,
+		newErrorResponse(http.StatusInternalServerError, `{"ok": false, "error_code": 500, "description": "Internal Server Error"}`),
+	)
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+	bot.SetRetryPolicy(testRetryPolicy())
+
+	_, err := bot.Send(NewMessage(ChatID, "test"))
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestRetryFloodControlTooLong(t *testing.T) {
+	var calls int
+	client := countingClient(&calls,
+		newErrorResponse(http.StatusTooManyRequests, `{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 60", "parameters": {"retry_after": 60}}`),
+	)
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+	bot.SetRetryPolicy(testRetryPolicy("sendMessage"))
+
+	_, err := bot.Send(NewMessage(ChatID, "test"))
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 60, apiErr.RetryAfter)
+}
+
+func TestRetryUploads(t *testing.T) {
+	failure := `{"ok": false, "error_code": 500, "description": "Internal Server Error"}`
+	success := `{"ok": true, "result": {"message_id": 1, "date": 0, "chat": {"id": 111, "type": "private"}}}`
+
+	var calls int
+	client := countingClient(&calls,
+		newErrorResponse(http.StatusInternalServerError, failure),
+		newOKResponse(success),
+	)
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+	bot.SetRetryPolicy(testRetryPolicy("sendPhoto"))
+
+	_, err := bot.Send(NewPhoto(ChatID, FileBytes{Name: "image.jpg", Bytes: []byte("data")}))
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+
+	calls = 0
+	client = countingClient(&calls,
+		newErrorResponse(http.StatusInternalServerError, failure),
+		newOKResponse(success),
+	)
+	bot = NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+	bot.SetRetryPolicy(testRetryPolicy("sendPhoto"))
+
+	_, err = bot.Send(NewPhoto(ChatID, FileReader{Name: "image.jpg", Reader: bytes.NewBufferString("data")}))
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff{BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+
+	for failures, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 4 * time.Second} {
+		d := b.Delay(failures)
+		require.GreaterOrEqual(t, d, max/2)
+		require.LessOrEqual(t, d, max)
+	}
+
+	uncapped := ExponentialBackoff{BaseDelay: time.Second}
+	for failures, max := range map[int]time.Duration{30: time.Second << 29, 35: math.MaxInt64, 64: math.MaxInt64, 1000: math.MaxInt64} {
+		d := uncapped.Delay(failures)
+		require.GreaterOrEqual(t, d, max/2, failures)
+		require.LessOrEqual(t, d, max, failures)
+	}
+}
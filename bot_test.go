@@ -2,6 +2,7 @@ package tgbotapi
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -270,3 +271,46 @@ func TestPrepareInputMediaForParams(t *testing.T) {
 		t.Error("Passthrough value was not the same")
 	}
 }
+
+type httpClientFunc func(req *http.Request) (*http.Response, error)
+
+func (f httpClientFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRequestContextCanceled(t *testing.T) {
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := bot.SendContext(ctx, NewMessage(ChatID, "test"))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestUploadFilesContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		// Read a bit of the body, then give up like a transport would when
+		// the request is canceled.
+		_, err := req.Body.Read(make([]byte, 1))
+		require.NoError(t, err)
+		cancel()
+
+		_, err = io.Copy(io.Discard, req.Body)
+		require.ErrorIs(t, err, context.Canceled)
+		return nil, req.Context().Err()
+	})
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	photo := NewPhoto(ChatID, FileBytes{Name: "image.jpg", Bytes: make([]byte, 1<<20)})
+	_, err := bot.RequestContext(ctx, photo)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,151 @@
+package tgbotapi
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// WebhookHealth reports the state of the webhook, as returned by
+// getWebhookInfo.
+type WebhookHealth struct {
+	// URL is the current webhook URL, empty if no webhook is set.
+	URL string
+	// PendingUpdateCount is the number of updates awaiting delivery.
+	PendingUpdateCount int
+	// LastErrorDate is the time of the most recent error delivering an
+	// update, zero if there was none.
+	LastErrorDate time.Time
+	// LastErrorMessage describes the most recent error delivering an update.
+	LastErrorMessage string
+	// LastSynchronizationErrorDate is the time of the most recent error
+	// synchronizing updates with Telegram datacenters, zero if there was
+	// none.
+	LastSynchronizationErrorDate time.Time
+	// Changed lists the settings that differed from the desired webhook,
+	// such as "url" or "allowed_updates". If not empty, setWebhook was
+	// called.
+	Changed []string
+}
+
+// NewWebhookHealth creates a WebhookHealth from info.
+func NewWebhookHealth(info WebhookInfo) WebhookHealth {
+	return WebhookHealth{
+		URL:                          info.URL,
+		PendingUpdateCount:           info.PendingUpdateCount,
+		LastErrorDate:                unixTime(info.LastErrorDate),
+		LastErrorMessage:             info.LastErrorMessage,
+		LastSynchronizationErrorDate: unixTime(info.LastSynchronizationErrorDate),
+	}
+}
+
+func unixTime(sec int) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(int64(sec), 0)
+}
+
+// HasErrorSince returns true if delivering an update failed after t.
+func (h WebhookHealth) HasErrorSince(t time.Time) bool {
+	return !h.LastErrorDate.IsZero() && h.LastErrorDate.After(t)
+}
+
+// Updated returns true if setWebhook was called.
+func (h WebhookHealth) Updated() bool {
+	return len(h.Changed) > 0
+}
+
+// defaultWebhookMaxConnections is the max_connections Telegram uses when
+// setWebhook omits it.
+const defaultWebhookMaxConnections = 40
+
+// webhookChanges returns the settings of config that differ from info.
+//
+// setWebhook resets an omitted max_connections to Telegram's default, so it
+// is compared against that. It clears an omitted ip_address, but Telegram
+// reports the address it currently uses either way, so ip_address is only
+// compared when config sets it. An omitted allowed_updates keeps the
+// previous setting.
+func webhookChanges(config WebhookConfig, info WebhookInfo) []string {
+	var changed []string
+
+	url := ""
+	if config.URL != nil {
+		url = config.URL.String()
+	}
+	if url != info.URL {
+		changed = append(changed, "url")
+	}
+
+	if (config.Certificate != nil) != info.HasCustomCertificate {
+		changed = append(changed, "certificate")
+	}
+
+	if config.IPAddress != "" && config.IPAddress != info.IPAddress {
+		changed = append(changed, "ip_address")
+	}
+
+	maxConnections := config.MaxConnections
+	if maxConnections == 0 {
+		maxConnections = defaultWebhookMaxConnections
+	}
+	if maxConnections != info.MaxConnections {
+		changed = append(changed, "max_connections")
+	}
+
+	if config.AllowedUpdates != nil && !sameUpdateTypes(config.AllowedUpdates, info.AllowedUpdates) {
+		changed = append(changed, "allowed_updates")
+	}
+
+	return changed
+}
+
+func sameUpdateTypes(a, b []string) bool {
+	a = slices.Clone(a)
+	b = slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+// EnsureWebhook sets the webhook to config, but only calls setWebhook if the
+// current webhook differs from it. It returns the health of the webhook.
+//
+// The URL, certificate presence, IP address, maximum connections and
+// allowed updates are compared. Telegram does not report the secret token
+// or the certificate contents, so changing only those requires calling
+// setWebhook directly.
+func (bot *BotAPI) EnsureWebhook(config WebhookConfig) (WebhookHealth, error) {
+	return bot.EnsureWebhookContext(context.Background(), config)
+}
+
+// EnsureWebhookContext is the same as EnsureWebhook, but uses ctx for the
+// requests.
+func (bot *BotAPI) EnsureWebhookContext(ctx context.Context, config WebhookConfig) (WebhookHealth, error) {
+	info, err := bot.GetWebhookInfoContext(ctx)
+	if err != nil {
+		return WebhookHealth{}, err
+	}
+
+	changed := webhookChanges(config, info)
+	if len(changed) == 0 {
+		return NewWebhookHealth(info), nil
+	}
+
+	if _, err := bot.RequestContext(ctx, config); err != nil {
+		return WebhookHealth{}, err
+	}
+
+	info, err = bot.GetWebhookInfoContext(ctx)
+	if err != nil {
+		return WebhookHealth{}, err
+	}
+
+	health := NewWebhookHealth(info)
+	health.Changed = changed
+
+	return health, nil
+}
@@ -0,0 +1,80 @@
+package tgbotapi
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookChanges(t *testing.T) {
+	config, err := NewWebhook("https://example.com/bot")
+	require.NoError(t, err)
+
+	info := WebhookInfo{
+		URL:            "https://example.com/bot",
+		MaxConnections: 40,
+		AllowedUpdates: []string{"message", "callback_query"},
+	}
+
+	require.Empty(t, webhookChanges(config, info))
+
+	config.AllowedUpdates = []string{"callback_query", "message"}
+	config.MaxConnections = 40
+	require.Empty(t, webhookChanges(config, info))
+
+	config.AllowedUpdates = []string{"message"}
+	config.MaxConnections = 100
+	config.Certificate = FileBytes{Name: "cert.pem"}
+	require.Equal(t, []string{"certificate", "max_connections", "allowed_updates"}, webhookChanges(config, info))
+
+	info.URL = ""
+	require.Contains(t, webhookChanges(config, info), "url")
+
+	// An omitted max_connections resets it to the default.
+	config.MaxConnections = 0
+	info.MaxConnections = 100
+	require.Contains(t, webhookChanges(config, info), "max_connections")
+}
+
+func TestEnsureWebhook(t *testing.T) {
+	infoResponse := `{"ok": true, "result": {"url": "https://example.com/bot", "pending_update_count": 3, "max_connections": 40, "last_error_date": 1700000000, "last_error_message": "Connection refused"}}`
+
+	var methods []string
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		method := path.Base(req.URL.Path)
+		methods = append(methods, method)
+		_, _ = io.Copy(io.Discard, req.Body)
+
+		if method == "getWebhookInfo" {
+			return newErrorResponse(http.StatusOK, infoResponse), nil
+		}
+		return newErrorResponse(http.StatusOK, `{"ok": true, "result": true}`), nil
+	})
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	config, err := NewWebhook("https://example.com/bot")
+	require.NoError(t, err)
+
+	health, err := bot.EnsureWebhook(config)
+	require.NoError(t, err)
+	require.False(t, health.Updated())
+	require.Equal(t, 3, health.PendingUpdateCount)
+	require.Equal(t, "Connection refused", health.LastErrorMessage)
+	require.True(t, health.HasErrorSince(time.Unix(1600000000, 0)))
+	require.False(t, health.HasErrorSince(time.Unix(1800000000, 0)))
+	require.Equal(t, []string{"getWebhookInfo"}, methods)
+
+	methods = nil
+	config, err = NewWebhook("https://example.com/other")
+	require.NoError(t, err)
+
+	health, err = bot.EnsureWebhook(config)
+	require.NoError(t, err)
+	require.Equal(t, []string{"url"}, health.Changed)
+	require.Equal(t, []string{"getWebhookInfo", "setWebhook", "getWebhookInfo"}, methods)
+}
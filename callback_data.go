@@ -0,0 +1,467 @@
+package tgbotapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxCallbackDataLength is the maximum length of callback data in bytes.
+const MaxCallbackDataLength = 64
+
+var (
+	// ErrCallbackDataTooLong is returned when encoded callback data does not
+	// fit in MaxCallbackDataLength bytes and there is no CallbackDataStore.
+	ErrCallbackDataTooLong = errors.New("callback data is longer than 64 bytes")
+	// ErrInvalidCallbackData is returned when callback data can't be decoded,
+	// or its signature does not match.
+	ErrInvalidCallbackData = errors.New("invalid callback data")
+)
+
+const (
+	callbackSeparator    = '|'
+	callbackEscape       = '\\'
+	callbackStoredPrefix = "~"
+	callbackSignatureLen = 6
+
+	callbackDataSweepInterval = 1024
+)
+
+// CallbackDataStore stores callback data that is too long to be sent to
+// Telegram, by a short key.
+type CallbackDataStore interface {
+	// Put stores data by key.
+	Put(ctx context.Context, key, data string) error
+	// Get returns the data stored by key, or false if there is none.
+	Get(ctx context.Context, key string) (string, bool, error)
+}
+
+type callbackDataEntry struct {
+	data    string
+	expires time.Time
+}
+
+// MemoryCallbackDataStore keeps callback data in memory for a limited time.
+type MemoryCallbackDataStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]callbackDataEntry
+	puts    int
+}
+
+// NewMemoryCallbackDataStore creates a MemoryCallbackDataStore keeping data
+// for ttl. Buttons sent earlier stop working afterwards.
+func NewMemoryCallbackDataStore(ttl time.Duration) *MemoryCallbackDataStore {
+	return &MemoryCallbackDataStore{
+		ttl:     ttl,
+		entries: make(map[string]callbackDataEntry),
+	}
+}
+
+// Put stores data by key.
+func (s *MemoryCallbackDataStore) Put(ctx context.Context, key, data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.entries[key] = callbackDataEntry{data: data, expires: now.Add(s.ttl)}
+
+	s.puts++
+	if s.puts%callbackDataSweepInterval == 0 {
+		for k, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Get returns the data stored by key.
+func (s *MemoryCallbackDataStore) Get(ctx context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false, nil
+	}
+
+	return entry.data, true, nil
+}
+
+// CallbackData is decoded callback data.
+type CallbackData struct {
+	// Action is the action the data was encoded with.
+	Action string
+	// Fields are the encoded field values.
+	Fields []string
+}
+
+// Scan sets the exported fields of the struct value points to from the
+// decoded fields, in order.
+func (d CallbackData) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("callback data: %T is not a pointer to a struct", value)
+	}
+
+	fields := callbackFields(v.Elem())
+	if len(fields) != len(d.Fields) {
+		return fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidCallbackData, len(fields), len(d.Fields))
+	}
+
+	for i, field := range fields {
+		if err := setCallbackField(field, d.Fields[i]); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidCallbackData, err)
+		}
+	}
+
+	return nil
+}
+
+// callbackFields returns the exported fields of the struct v.
+func callbackFields(v reflect.Value) []reflect.Value {
+	var fields []reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() {
+			fields = append(fields, v.Field(i))
+		}
+	}
+
+	return fields
+}
+
+func formatCallbackField(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("callback data: unsupported field type %s", v.Type())
+	}
+}
+
+func setCallbackField(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		v.SetBool(s == "1")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
+
+func escapeCallbackField(s string) string {
+	if !strings.ContainsAny(s, string([]rune{callbackSeparator, callbackEscape})) {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if r == callbackSeparator || r == callbackEscape {
+			b.WriteRune(callbackEscape)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// splitCallbackData splits s at unescaped separators.
+func splitCallbackData(s string) []string {
+	var (
+		parts   []string
+		current strings.Builder
+		escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == callbackEscape:
+			escaped = true
+		case r == callbackSeparator:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	return append(parts, current.String())
+}
+
+type callbackRoute struct {
+	handle func(ctx context.Context, bot *BotAPI, query *CallbackQuery, data CallbackData) error
+}
+
+// CallbackCodec encodes typed values as callback data of inline keyboard
+// buttons, and routes callback queries to handlers by action.
+//
+// Callback data consists of an action followed by the exported fields of a
+// struct, which may be strings, booleans or numbers. With a secret, the data
+// is signed, so data changed by a client is rejected. Data longer than
+// MaxCallbackDataLength bytes is kept in a CallbackDataStore if one is set,
+// and only a short key is sent to Telegram.
+//
+// CallbackCodec implements Handler.
+type CallbackCodec struct {
+	secret []byte
+	store  CallbackDataStore
+
+	mu     sync.RWMutex
+	routes map[string]callbackRoute
+}
+
+// NewCallbackCodec creates a CallbackCodec without a secret or store.
+func NewCallbackCodec() *CallbackCodec {
+	return &CallbackCodec{
+		routes: make(map[string]callbackRoute),
+	}
+}
+
+// SetSecret sets the secret the callback data is signed with.
+func (c *CallbackCodec) SetSecret(secret []byte) {
+	c.secret = secret
+}
+
+// SetStore sets the store for callback data that is too long.
+func (c *CallbackCodec) SetStore(store CallbackDataStore) {
+	c.store = store
+}
+
+func (c *CallbackCodec) sign(payload string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignatureLen])
+}
+
+// Encode encodes action and the exported fields of value, a struct or a
+// pointer to one, as callback data. value may be nil to encode only the
+// action.
+func (c *CallbackCodec) Encode(ctx context.Context, action string, value any) (string, error) {
+	if action == "" || strings.HasPrefix(action, callbackStoredPrefix) || strings.ContainsAny(action, string([]rune{callbackSeparator, callbackEscape})) {
+		return "", fmt.Errorf("callback data: invalid action %q", action)
+	}
+
+	parts := []string{action}
+
+	if value != nil {
+		v := reflect.Indirect(reflect.ValueOf(value))
+		if v.Kind() != reflect.Struct {
+			return "", fmt.Errorf("callback data: %T is not a struct", value)
+		}
+
+		for _, field := range callbackFields(v) {
+			s, err := formatCallbackField(field)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, escapeCallbackField(s))
+		}
+	}
+
+	if c.secret != nil {
+		parts = append(parts, c.sign(strings.Join(parts, string(callbackSeparator))))
+	}
+
+	payload := strings.Join(parts, string(callbackSeparator))
+	if len(payload) <= MaxCallbackDataLength {
+		return payload, nil
+	}
+
+	if c.store == nil {
+		return "", fmt.Errorf("%w: action %q has %d bytes", ErrCallbackDataTooLong, action, len(payload))
+	}
+
+	key := make([]byte, 12)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	ref := callbackStoredPrefix + base64.RawURLEncoding.EncodeToString(key)
+	if err := c.store.Put(ctx, ref, payload); err != nil {
+		return "", err
+	}
+
+	return ref, nil
+}
+
+// Button creates an inline keyboard button with callback data encoded from
+// action and value.
+func (c *CallbackCodec) Button(ctx context.Context, text, action string, value any) (InlineKeyboardButton, error) {
+	data, err := c.Encode(ctx, action, value)
+	if err != nil {
+		return InlineKeyboardButton{}, err
+	}
+
+	return NewInlineKeyboardButtonData(text, data), nil
+}
+
+// Decode decodes callback data created by Encode.
+func (c *CallbackCodec) Decode(ctx context.Context, data string) (CallbackData, error) {
+	payload, ok, err := c.load(ctx, data)
+	if err != nil {
+		return CallbackData{}, err
+	}
+	if !ok {
+		return CallbackData{}, fmt.Errorf("%w: unknown or expired key", ErrInvalidCallbackData)
+	}
+
+	return c.decode(payload)
+}
+
+// load returns the payload of data, which is looked up in the store if data
+// is a key. It returns false if the key is unknown.
+func (c *CallbackCodec) load(ctx context.Context, data string) (string, bool, error) {
+	if !strings.HasPrefix(data, callbackStoredPrefix) {
+		return data, true, nil
+	}
+
+	if c.store == nil {
+		return "", false, nil
+	}
+
+	return c.store.Get(ctx, data)
+}
+
+// decode verifies and splits payload.
+func (c *CallbackCodec) decode(payload string) (CallbackData, error) {
+	parts := splitCallbackData(payload)
+
+	if c.secret != nil {
+		if len(parts) < 2 {
+			return CallbackData{}, ErrInvalidCallbackData
+		}
+
+		i := strings.LastIndexByte(payload, callbackSeparator)
+		if !hmac.Equal([]byte(payload[i+1:]), []byte(c.sign(payload[:i]))) {
+			return CallbackData{}, fmt.Errorf("%w: signature mismatch", ErrInvalidCallbackData)
+		}
+		parts = parts[:len(parts)-1]
+	}
+
+	return CallbackData{Action: parts[0], Fields: parts[1:]}, nil
+}
+
+// HandleCallback registers fn for callback queries with data encoded with
+// action. The fields are decoded into a value of type T, a struct.
+func HandleCallback[T any](c *CallbackCodec, action string, fn func(ctx context.Context, bot *BotAPI, query *CallbackQuery, data T) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.routes[action] = callbackRoute{
+		handle: func(ctx context.Context, bot *BotAPI, query *CallbackQuery, data CallbackData) error {
+			var value T
+			if err := data.Scan(&value); err != nil {
+				return err
+			}
+
+			return fn(ctx, bot, query, value)
+		},
+	}
+}
+
+// HandleUpdate decodes the data of a callback query and calls the handler
+// registered for its action.
+//
+// It returns ErrFallthrough if the update is not a callback query, or no
+// handler is registered for the action. Data that can't be decoded, and
+// keys that are unknown or expired in the store, return an error matching
+// ErrInvalidCallbackData, so the query can be answered as expired.
+func (c *CallbackCodec) HandleUpdate(ctx context.Context, bot *BotAPI, update Update) error {
+	query := update.CallbackQuery
+	if query == nil || query.Data == "" {
+		return ErrFallthrough
+	}
+
+	payload, ok, err := c.load(ctx, query.Data)
+	if err != nil {
+		return err
+	}
+
+	// Without a store, keys were not created by this codec.
+	if !ok && c.store == nil {
+		return ErrFallthrough
+	}
+	if !ok {
+		return fmt.Errorf("%w: unknown or expired key", ErrInvalidCallbackData)
+	}
+
+	// Leave data of other handlers alone, without checking its signature.
+	if !c.routed(splitCallbackData(payload)[0]) {
+		return ErrFallthrough
+	}
+
+	data, err := c.decode(payload)
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	route, ok := c.routes[data.Action]
+	c.mu.RUnlock()
+	if !ok {
+		return ErrFallthrough
+	}
+
+	return route.handle(ctx, bot, query, data)
+}
+
+func (c *CallbackCodec) routed(action string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.routes[action]
+	return ok
+}
@@ -0,0 +1,103 @@
+package tgbotapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pageData struct {
+	Query string
+	Page  int
+	Desc  bool
+}
+
+func TestCallbackCodecRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c := NewCallbackCodec()
+
+	data, err := c.Encode(ctx, "page", pageData{Query: "a|b\\c", Page: 3, Desc: true})
+	require.NoError(t, err)
+	require.Equal(t, `page|a\|b\\c|3|1`, data)
+
+	decoded, err := c.Decode(ctx, data)
+	require.NoError(t, err)
+	require.Equal(t, "page", decoded.Action)
+
+	var value pageData
+	require.NoError(t, decoded.Scan(&value))
+	require.Equal(t, pageData{Query: "a|b\\c", Page: 3, Desc: true}, value)
+}
+
+func TestCallbackCodecSigned(t *testing.T) {
+	ctx := context.Background()
+	c := NewCallbackCodec()
+	c.SetSecret([]byte("secret"))
+
+	data, err := c.Encode(ctx, "page", pageData{Page: 3})
+	require.NoError(t, err)
+
+	_, err = c.Decode(ctx, data)
+	require.NoError(t, err)
+
+	_, err = c.Decode(ctx, strings.Replace(data, "|3|", "|4|", 1))
+	require.ErrorIs(t, err, ErrInvalidCallbackData)
+}
+
+func TestCallbackCodecTooLong(t *testing.T) {
+	ctx := context.Background()
+	c := NewCallbackCodec()
+	value := pageData{Query: strings.Repeat("x", 64)}
+
+	_, err := c.Encode(ctx, "page", value)
+	require.ErrorIs(t, err, ErrCallbackDataTooLong)
+
+	_, err = c.Button(ctx, "Next", "page", value)
+	require.ErrorIs(t, err, ErrCallbackDataTooLong)
+
+	c.SetStore(NewMemoryCallbackDataStore(time.Hour))
+
+	data, err := c.Encode(ctx, "page", value)
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(data), MaxCallbackDataLength)
+
+	decoded, err := c.Decode(ctx, data)
+	require.NoError(t, err)
+
+	var got pageData
+	require.NoError(t, decoded.Scan(&got))
+	require.Equal(t, value, got)
+
+	_, err = c.Decode(ctx, "~unknown")
+	require.ErrorIs(t, err, ErrInvalidCallbackData)
+}
+
+func TestCallbackCodecHandleUpdate(t *testing.T) {
+	ctx := context.Background()
+	c := NewCallbackCodec()
+	c.SetSecret([]byte("secret"))
+
+	var got pageData
+	HandleCallback(c, "page", func(ctx context.Context, bot *BotAPI, query *CallbackQuery, data pageData) error {
+		got = data
+		return nil
+	})
+
+	data, err := c.Encode(ctx, "page", pageData{Query: "go", Page: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, c.HandleUpdate(ctx, nil, Update{CallbackQuery: &CallbackQuery{Data: data}}))
+	require.Equal(t, pageData{Query: "go", Page: 2}, got)
+
+	require.ErrorIs(t, c.HandleUpdate(ctx, nil, Update{CallbackQuery: &CallbackQuery{Data: "other|1"}}), ErrFallthrough)
+	require.ErrorIs(t, c.HandleUpdate(ctx, nil, Update{CallbackQuery: &CallbackQuery{Data: "page|go|9|0|forged"}}), ErrInvalidCallbackData)
+	require.ErrorIs(t, c.HandleUpdate(ctx, nil, textUpdate(1, "hi")), ErrFallthrough)
+	require.ErrorIs(t, c.HandleUpdate(ctx, nil, Update{CallbackQuery: &CallbackQuery{Data: "~other"}}), ErrFallthrough)
+
+	// With a store, an unknown key was created by the codec and expired.
+	c.SetStore(NewMemoryCallbackDataStore(time.Hour))
+	require.ErrorIs(t, c.HandleUpdate(ctx, nil, Update{CallbackQuery: &CallbackQuery{Data: "~other"}}), ErrInvalidCallbackData)
+}
@@ -0,0 +1,390 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// CommandHandlerFunc handles a command sent in message. args holds the parsed
+// command arguments.
+type CommandHandlerFunc func(ctx context.Context, bot *BotAPI, message *Message, args CommandArgs) error
+
+// Command is a bot command with its handler and the descriptions published
+// to Telegram.
+type Command struct {
+	// Name is the command without the leading slash, for example "start".
+	Name string
+	// Description is shown in the command list. Commands without any
+	// description are handled but not published.
+	Description string
+	// Descriptions are translated descriptions, by IETF language code.
+	Descriptions map[string]string
+	// Scopes the command is published for. If empty, the default scope is
+	// used.
+	Scopes []BotCommandScope
+	// Handler is called when the command is received.
+	Handler CommandHandlerFunc
+}
+
+var commandNameRegexp = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+func (c Command) validate() error {
+	if !commandNameRegexp.MatchString(c.Name) {
+		return fmt.Errorf("invalid command name %q", c.Name)
+	}
+
+	if c.Handler == nil {
+		return fmt.Errorf("command %q has no handler", c.Name)
+	}
+
+	return nil
+}
+
+// CommandRegistry maps commands to handlers. It implements Handler, and can
+// publish the registered commands with setMyCommands, so the command list
+// shown by Telegram always matches the handled commands.
+type CommandRegistry struct {
+	mu        sync.RWMutex
+	commands  []Command
+	usernames map[*BotAPI]string
+}
+
+// NewCommandRegistry creates an empty CommandRegistry.
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		usernames: make(map[*BotAPI]string),
+	}
+}
+
+// Register adds a command. Registering a name twice replaces the previous
+// command.
+func (r *CommandRegistry) Register(cmd Command) error {
+	if err := cmd.validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, c := range r.commands {
+		if c.Name == cmd.Name {
+			r.commands[i] = cmd
+			return nil
+		}
+	}
+
+	r.commands = append(r.commands, cmd)
+	return nil
+}
+
+// Handle registers a command in the default scope.
+func (r *CommandRegistry) Handle(name, description string, handler CommandHandlerFunc) error {
+	return r.Register(Command{
+		Name:        name,
+		Description: description,
+		Handler:     handler,
+	})
+}
+
+// Commands returns the registered commands.
+func (r *CommandRegistry) Commands() []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]Command(nil), r.commands...)
+}
+
+func (r *CommandRegistry) lookup(name string) (Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+
+	return Command{}, false
+}
+
+// username returns the bot's username, fetching it with getMe the first
+// time.
+func (r *CommandRegistry) username(ctx context.Context, bot *BotAPI) (string, error) {
+	r.mu.RLock()
+	username, ok := r.usernames[bot]
+	r.mu.RUnlock()
+	if ok {
+		return username, nil
+	}
+
+	me, err := bot.GetMeContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.usernames[bot] = me.UserName
+	r.mu.Unlock()
+
+	return me.UserName, nil
+}
+
+// HandleUpdate calls the handler of the command in the update's message.
+//
+// It returns ErrFallthrough if the update is not a registered command, or if
+// the command is addressed to another bot, as in "/start@other_bot".
+func (r *CommandRegistry) HandleUpdate(ctx context.Context, bot *BotAPI, update Update) error {
+	message := update.Message
+	if message == nil || !message.IsCommand() {
+		return ErrFallthrough
+	}
+
+	cmd, ok := r.lookup(strings.ToLower(message.Command()))
+	if !ok {
+		return ErrFallthrough
+	}
+
+	if withAt := message.CommandWithAt(); strings.Contains(withAt, "@") {
+		username, err := r.username(ctx, bot)
+		if err != nil {
+			return err
+		}
+
+		target := withAt[strings.Index(withAt, "@")+1:]
+		if !strings.EqualFold(target, username) {
+			return ErrFallthrough
+		}
+	}
+
+	return cmd.Handler(ctx, bot, message, ParseCommandArgs(message.CommandArguments()))
+}
+
+// SetMyCommandsConfigs returns the setMyCommands requests needed to publish
+// the registered commands, one for every scope and language.
+func (r *CommandRegistry) SetMyCommandsConfigs() []SetMyCommandsConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type key struct {
+		scope    BotCommandScope
+		language string
+	}
+
+	var keys []key
+	lists := make(map[key][]BotCommand)
+
+	add := func(k key, command BotCommand) {
+		if _, ok := lists[k]; !ok {
+			keys = append(keys, k)
+		}
+		lists[k] = append(lists[k], command)
+	}
+
+	for _, cmd := range r.commands {
+		scopes := cmd.Scopes
+		if len(scopes) == 0 {
+			scopes = []BotCommandScope{NewBotCommandScopeDefault()}
+		}
+
+		languages := make([]string, 0, len(cmd.Descriptions))
+		for language := range cmd.Descriptions {
+			languages = append(languages, language)
+		}
+		sort.Strings(languages)
+
+		for _, scope := range scopes {
+			if cmd.Description != "" {
+				add(key{scope: scope}, BotCommand{Command: cmd.Name, Description: cmd.Description})
+			}
+
+			for _, language := range languages {
+				add(key{scope: scope, language: language}, BotCommand{
+					Command:     cmd.Name,
+					Description: cmd.Descriptions[language],
+				})
+			}
+		}
+	}
+
+	configs := make([]SetMyCommandsConfig, 0, len(keys))
+	for _, k := range keys {
+		scope := k.scope
+		configs = append(configs, SetMyCommandsConfig{
+			Commands:     lists[k],
+			Scope:        &scope,
+			LanguageCode: k.language,
+		})
+	}
+
+	return configs
+}
+
+// Publish sends the registered commands to Telegram with setMyCommands.
+func (r *CommandRegistry) Publish(ctx context.Context, bot *BotAPI) error {
+	for _, config := range r.SetMyCommandsConfigs() {
+		if _, err := bot.RequestContext(ctx, config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ErrMissingArgument is returned when a requested command argument is not
+// present.
+var ErrMissingArgument = errors.New("missing argument")
+
+// CommandArgs are the parsed arguments of a command.
+//
+// Arguments are separated by whitespace, and may be quoted with single or
+// double quotes to include whitespace. Arguments of the form "--name=value"
+// or "--name" are flags, everything else is positional.
+type CommandArgs struct {
+	// Raw is the unparsed argument string.
+	Raw string
+	// Positional are the arguments that are not flags, in order.
+	Positional []string
+	// Flags are the flag arguments by name. Flags without a value are
+	// set to an empty string.
+	Flags map[string]string
+}
+
+// ParseCommandArgs parses the arguments of a command, as returned by
+// Message.CommandArguments.
+func ParseCommandArgs(raw string) CommandArgs {
+	args := CommandArgs{
+		Raw:   raw,
+		Flags: make(map[string]string),
+	}
+
+	for _, token := range splitArgs(raw) {
+		if strings.HasPrefix(token, "--") && len(token) > 2 {
+			name, value, _ := strings.Cut(token[2:], "=")
+			args.Flags[name] = value
+			continue
+		}
+
+		args.Positional = append(args.Positional, token)
+	}
+
+	return args
+}
+
+// splitArgs splits s at whitespace outside of quotes.
+func splitArgs(s string) []string {
+	var (
+		tokens  []string
+		current strings.Builder
+		quote   rune
+		inToken bool
+	)
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+// Len returns the number of positional arguments.
+func (a CommandArgs) Len() int {
+	return len(a.Positional)
+}
+
+// Arg returns the positional argument at index i.
+func (a CommandArgs) Arg(i int) (string, error) {
+	if i < 0 || i >= len(a.Positional) {
+		return "", fmt.Errorf("argument %d: %w", i+1, ErrMissingArgument)
+	}
+
+	return a.Positional[i], nil
+}
+
+// Int returns the positional argument at index i as an int.
+func (a CommandArgs) Int(i int) (int, error) {
+	s, err := a.Arg(i)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(s)
+}
+
+// Int64 returns the positional argument at index i as an int64.
+func (a CommandArgs) Int64(i int) (int64, error) {
+	s, err := a.Arg(i)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// Float returns the positional argument at index i as a float64.
+func (a CommandArgs) Float(i int) (float64, error) {
+	s, err := a.Arg(i)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
+
+// Flag returns the value of the flag name, and whether it was set.
+func (a CommandArgs) Flag(name string) (string, bool) {
+	value, ok := a.Flags[name]
+	return value, ok
+}
+
+// BoolFlag returns true if the flag name was set without a value, or with a
+// value that parses as true.
+func (a CommandArgs) BoolFlag(name string) bool {
+	value, ok := a.Flags[name]
+	if !ok {
+		return false
+	}
+	if value == "" {
+		return true
+	}
+
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+// IntFlag returns the value of the flag name as an int.
+func (a CommandArgs) IntFlag(name string) (int, error) {
+	value, ok := a.Flags[name]
+	if !ok {
+		return 0, fmt.Errorf("flag --%s: %w", name, ErrMissingArgument)
+	}
+
+	return strconv.Atoi(value)
+}
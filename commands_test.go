@@ -0,0 +1,101 @@
+package tgbotapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func commandUpdate(text string) Update {
+	command := text
+	for i, r := range text {
+		if r == ' ' {
+			command = text[:i]
+			break
+		}
+	}
+
+	return Update{Message: &Message{
+		Chat:     Chat{ID: 1, Type: "group"},
+		Text:     text,
+		Entities: []MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}}
+}
+
+func TestParseCommandArgs(t *testing.T) {
+	args := ParseCommandArgs(`42 "hello world" --force --limit=10 -5`)
+
+	require.Equal(t, []string{"42", "hello world", "-5"}, args.Positional)
+	n, err := args.Int(0)
+	require.NoError(t, err)
+	require.Equal(t, 42, n)
+	n, err = args.Int(2)
+	require.NoError(t, err)
+	require.Equal(t, -5, n)
+	require.True(t, args.BoolFlag("force"))
+	limit, err := args.IntFlag("limit")
+	require.NoError(t, err)
+	require.Equal(t, 10, limit)
+
+	_, err = args.Arg(3)
+	require.ErrorIs(t, err, ErrMissingArgument)
+}
+
+func TestCommandRegistryHandleUpdate(t *testing.T) {
+	client := prepareHttpClient(t)
+	defer client.ctrl.Finish()
+	expectGetMe(t, client)
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	registry := NewCommandRegistry()
+	var got []string
+	require.NoError(t, registry.Handle("echo", "Echo the arguments", func(ctx context.Context, bot *BotAPI, message *Message, args CommandArgs) error {
+		got = append(got, args.Raw)
+		return nil
+	}))
+	require.Error(t, registry.Handle("Bad-Name", "", nil))
+
+	ctx := context.Background()
+	require.NoError(t, registry.HandleUpdate(ctx, bot, commandUpdate("/echo one")))
+	require.NoError(t, registry.HandleUpdate(ctx, bot, commandUpdate("/echo@my_bot two")))
+	require.ErrorIs(t, registry.HandleUpdate(ctx, bot, commandUpdate("/echo@other_bot three")), ErrFallthrough)
+	require.ErrorIs(t, registry.HandleUpdate(ctx, bot, commandUpdate("/unknown")), ErrFallthrough)
+	require.Equal(t, []string{"one", "two"}, got)
+}
+
+func TestCommandRegistryPublish(t *testing.T) {
+	registry := NewCommandRegistry()
+	noop := func(ctx context.Context, bot *BotAPI, message *Message, args CommandArgs) error { return nil }
+
+	require.NoError(t, registry.Register(Command{
+		Name:         "start",
+		Description:  "Start the bot",
+		Descriptions: map[string]string{"de": "Bot starten"},
+		Handler:      noop,
+	}))
+	require.NoError(t, registry.Register(Command{
+		Name:        "ban",
+		Description: "Ban a user",
+		Scopes:      []BotCommandScope{NewBotCommandScopeAllChatAdministrators()},
+		Handler:     noop,
+	}))
+	require.NoError(t, registry.Register(Command{Name: "secret", Handler: noop}))
+
+	configs := registry.SetMyCommandsConfigs()
+	require.Len(t, configs, 3)
+	require.Equal(t, []BotCommand{{Command: "start", Description: "Start the bot"}}, configs[0].Commands)
+	require.Equal(t, "de", configs[1].LanguageCode)
+	require.Equal(t, []BotCommand{{Command: "start", Description: "Bot starten"}}, configs[1].Commands)
+	require.Equal(t, "all_chat_administrators", configs[2].Scope.Type)
+
+	var methods []string
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		methods = append(methods, req.URL.Path)
+		return newOKResponse(`{"ok": true, "result": true}`), nil
+	}))
+	require.NoError(t, registry.Publish(context.Background(), bot))
+	require.Len(t, methods, 3)
+}
@@ -0,0 +1,240 @@
+package tgbotapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Conversation is the conversation an update belongs to, as seen by a state
+// handler. Changes to it are saved after the handler returns without an
+// error.
+type Conversation struct {
+	Key ConversationKey
+	// State is the name of the current state. It is empty in entry
+	// handlers.
+	State string
+	// Data is the data collected during the conversation.
+	Data map[string]string
+
+	next  string
+	ended bool
+}
+
+// Transition moves the conversation to state after the handler returns.
+func (c *Conversation) Transition(state string) {
+	c.next = state
+	c.ended = false
+}
+
+// End ends the conversation after the handler returns, removing its state
+// and data.
+func (c *Conversation) End() {
+	c.ended = true
+}
+
+// Get returns the value of the data key.
+func (c *Conversation) Get(key string) string {
+	return c.Data[key]
+}
+
+// Set sets the value of the data key.
+func (c *Conversation) Set(key, value string) {
+	if c.Data == nil {
+		c.Data = make(map[string]string)
+	}
+	c.Data[key] = value
+}
+
+// StateHandlerFunc handles an update in a conversation.
+type StateHandlerFunc func(ctx context.Context, bot *BotAPI, update Update, conv *Conversation) error
+
+type conversationEntry struct {
+	filter Filter
+	fn     StateHandlerFunc
+}
+
+// ConversationHandler runs multi-step conversations, such as forms or
+// wizards, with every user in every chat. It implements Handler.
+//
+// A conversation starts when an update matches an entry point. Its handler
+// moves the conversation to a state with Transition. Following updates from
+// the same user in the same chat are passed to the handler of the current
+// state, until one of them calls End.
+type ConversationHandler struct {
+	store ConversationStore
+
+	mu             sync.RWMutex
+	entries        []conversationEntry
+	states         map[string]StateHandlerFunc
+	timeout        time.Duration
+	onTimeout      StateHandlerFunc
+	cancelCommands []string
+	onCancel       StateHandlerFunc
+}
+
+// NewConversationHandler creates a ConversationHandler saving conversations
+// to store.
+func NewConversationHandler(store ConversationStore) *ConversationHandler {
+	return &ConversationHandler{
+		store:  store,
+		states: make(map[string]StateHandlerFunc),
+	}
+}
+
+// Entry adds an entry point, starting a conversation with updates that
+// match filter when none is active. fn should call Transition.
+func (h *ConversationHandler) Entry(filter Filter, fn StateHandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, conversationEntry{filter: filter, fn: fn})
+}
+
+// Handle sets the handler of state.
+func (h *ConversationHandler) Handle(state string, fn StateHandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.states[state] = fn
+}
+
+// SetTimeout ends conversations without updates for longer than timeout.
+// The timeout is checked when the next update arrives, which is passed to
+// onTimeout, if not nil, and then handled as if there was no conversation.
+func (h *ConversationHandler) SetTimeout(timeout time.Duration, onTimeout StateHandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.timeout = timeout
+	h.onTimeout = onTimeout
+}
+
+// SetCancelCommands sets commands, without the leading slash, that end the
+// current conversation. onCancel, if not nil, is called before it ends.
+func (h *ConversationHandler) SetCancelCommands(onCancel StateHandlerFunc, commands ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cancelCommands = commands
+	h.onCancel = onCancel
+}
+
+// Start starts a conversation in state, for example to begin a conversation
+// from elsewhere than an update. An active conversation is replaced.
+func (h *ConversationHandler) Start(ctx context.Context, key ConversationKey, state string, data map[string]string) error {
+	return h.store.Set(ctx, key, ConversationState{
+		State:     state,
+		Data:      data,
+		UpdatedAt: time.Now(),
+	})
+}
+
+// Current returns the state of the active conversation, or false if there
+// is none.
+func (h *ConversationHandler) Current(ctx context.Context, key ConversationKey) (ConversationState, bool, error) {
+	return h.store.Get(ctx, key)
+}
+
+// HandleUpdate passes update to the handler of the current state of its
+// conversation, or to a matching entry point.
+//
+// It returns ErrFallthrough if the update is not part of a conversation and
+// matches no entry point.
+func (h *ConversationHandler) HandleUpdate(ctx context.Context, bot *BotAPI, update Update) error {
+	key, ok := ConversationKeyOf(update)
+	if !ok {
+		return ErrFallthrough
+	}
+
+	h.mu.RLock()
+	timeout := h.timeout
+	onTimeout := h.onTimeout
+	cancelCommands := h.cancelCommands
+	onCancel := h.onCancel
+	h.mu.RUnlock()
+
+	state, active, err := h.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if active && timeout > 0 && time.Since(state.UpdatedAt) > timeout {
+		if err := h.end(ctx, bot, update, key, state, onTimeout); err != nil {
+			return err
+		}
+		active = false
+	}
+
+	if active && len(cancelCommands) > 0 && CommandFilter(cancelCommands...)(update) {
+		return h.end(ctx, bot, update, key, state, onCancel)
+	}
+
+	if !active {
+		return h.enter(ctx, bot, update, key)
+	}
+
+	h.mu.RLock()
+	fn, ok := h.states[state.State]
+	h.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("conversation %s is in unknown state %q", key, state.State)
+	}
+
+	return h.run(ctx, bot, update, key, state, fn)
+}
+
+// end removes the conversation, calling fn first if it is not nil.
+func (h *ConversationHandler) end(ctx context.Context, bot *BotAPI, update Update, key ConversationKey, state ConversationState, fn StateHandlerFunc) error {
+	if fn != nil {
+		conv := &Conversation{Key: key, State: state.State, Data: state.Data}
+		if err := fn(ctx, bot, update, conv); err != nil {
+			return err
+		}
+	}
+
+	return h.store.Delete(ctx, key)
+}
+
+// enter passes update to the first matching entry point.
+func (h *ConversationHandler) enter(ctx context.Context, bot *BotAPI, update Update, key ConversationKey) error {
+	h.mu.RLock()
+	entries := h.entries
+	h.mu.RUnlock()
+
+	for _, entry := range entries {
+		if entry.filter == nil || entry.filter(update) {
+			return h.run(ctx, bot, update, key, ConversationState{}, entry.fn)
+		}
+	}
+
+	return ErrFallthrough
+}
+
+// run calls fn and saves the resulting conversation.
+func (h *ConversationHandler) run(ctx context.Context, bot *BotAPI, update Update, key ConversationKey, state ConversationState, fn StateHandlerFunc) error {
+	conv := &Conversation{
+		Key:   key,
+		State: state.State,
+		Data:  state.Data,
+		next:  state.State,
+	}
+
+	if err := fn(ctx, bot, update, conv); err != nil {
+		return err
+	}
+
+	if conv.ended || conv.next == "" {
+		if state.State == "" {
+			return nil
+		}
+		return h.store.Delete(ctx, key)
+	}
+
+	return h.store.Set(ctx, key, ConversationState{
+		State:     conv.next,
+		Data:      conv.Data,
+		UpdatedAt: time.Now(),
+	})
+}
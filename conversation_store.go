@@ -0,0 +1,224 @@
+package tgbotapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConversationKey identifies a conversation with a user in a chat.
+type ConversationKey struct {
+	ChatID int64
+	UserID int64
+}
+
+// ConversationKeyOf returns the key of the conversation update belongs to,
+// or false if the update has no chat or sender.
+func ConversationKeyOf(update Update) (ConversationKey, bool) {
+	chat := update.FromChat()
+	user := update.SentFrom()
+	if chat == nil || user == nil {
+		return ConversationKey{}, false
+	}
+
+	return ConversationKey{ChatID: chat.ID, UserID: user.ID}, true
+}
+
+// String returns the key as "chatID:userID".
+func (k ConversationKey) String() string {
+	return strconv.FormatInt(k.ChatID, 10) + ":" + strconv.FormatInt(k.UserID, 10)
+}
+
+func parseConversationKey(s string) (ConversationKey, error) {
+	chat, user, ok := strings.Cut(s, ":")
+	if !ok {
+		return ConversationKey{}, errors.New("invalid conversation key")
+	}
+
+	chatID, err := strconv.ParseInt(chat, 10, 64)
+	if err != nil {
+		return ConversationKey{}, err
+	}
+
+	userID, err := strconv.ParseInt(user, 10, 64)
+	if err != nil {
+		return ConversationKey{}, err
+	}
+
+	return ConversationKey{ChatID: chatID, UserID: userID}, nil
+}
+
+// ConversationState is the stored state of a conversation.
+type ConversationState struct {
+	// State is the name of the current state.
+	State string `json:"state"`
+	// Data is the data collected during the conversation.
+	Data map[string]string `json:"data,omitempty"`
+	// UpdatedAt is when the conversation last changed.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ConversationStore stores the state of conversations.
+type ConversationStore interface {
+	// Get returns the state of the conversation, or false if there is
+	// none.
+	Get(ctx context.Context, key ConversationKey) (ConversationState, bool, error)
+	// Set saves the state of the conversation.
+	Set(ctx context.Context, key ConversationKey, state ConversationState) error
+	// Delete removes the conversation.
+	Delete(ctx context.Context, key ConversationKey) error
+}
+
+// MemoryConversationStore keeps conversations in memory.
+type MemoryConversationStore struct {
+	mu            sync.Mutex
+	conversations map[ConversationKey]ConversationState
+}
+
+// NewMemoryConversationStore creates an empty MemoryConversationStore.
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		conversations: make(map[ConversationKey]ConversationState),
+	}
+}
+
+// Get returns the state of the conversation.
+func (s *MemoryConversationStore) Get(ctx context.Context, key ConversationKey) (ConversationState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.conversations[key]
+	return copyConversationState(state), ok, nil
+}
+
+// Set saves the state of the conversation.
+func (s *MemoryConversationStore) Set(ctx context.Context, key ConversationKey, state ConversationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conversations[key] = copyConversationState(state)
+	return nil
+}
+
+// Delete removes the conversation.
+func (s *MemoryConversationStore) Delete(ctx context.Context, key ConversationKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conversations, key)
+	return nil
+}
+
+func copyConversationState(state ConversationState) ConversationState {
+	if state.Data != nil {
+		data := make(map[string]string, len(state.Data))
+		for k, v := range state.Data {
+			data[k] = v
+		}
+		state.Data = data
+	}
+
+	return state
+}
+
+// FileConversationStore keeps conversations in memory and saves all of them
+// to a JSON file on every change, so they survive restarts. It suits bots
+// with a moderate number of active conversations.
+type FileConversationStore struct {
+	path string
+
+	mu     sync.Mutex
+	memory *MemoryConversationStore
+}
+
+// NewFileConversationStore creates a FileConversationStore saving to path,
+// and loads the conversations saved there before.
+func NewFileConversationStore(path string) (*FileConversationStore, error) {
+	s := &FileConversationStore{
+		path:   path,
+		memory: NewMemoryConversationStore(),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved map[string]ConversationState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+
+	for k, state := range saved {
+		key, err := parseConversationKey(k)
+		if err != nil {
+			return nil, err
+		}
+		s.memory.conversations[key] = state
+	}
+
+	return s, nil
+}
+
+// Get returns the state of the conversation.
+func (s *FileConversationStore) Get(ctx context.Context, key ConversationKey) (ConversationState, bool, error) {
+	return s.memory.Get(ctx, key)
+}
+
+// Set saves the state of the conversation. If the file cannot be written,
+// the state is left unchanged.
+func (s *FileConversationStore) Set(ctx context.Context, key ConversationKey, state ConversationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.save(key, &state); err != nil {
+		return err
+	}
+
+	return s.memory.Set(ctx, key, state)
+}
+
+// Delete removes the conversation. If the file cannot be written, the
+// conversation is kept.
+func (s *FileConversationStore) Delete(ctx context.Context, key ConversationKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.save(key, nil); err != nil {
+		return err
+	}
+
+	return s.memory.Delete(ctx, key)
+}
+
+// save writes all conversations to the file, with the state of key replaced
+// by state, or removed if state is nil. It must be called with s.mu held.
+func (s *FileConversationStore) save(key ConversationKey, state *ConversationState) error {
+	s.memory.mu.Lock()
+	saved := make(map[string]ConversationState, len(s.memory.conversations)+1)
+	for k, v := range s.memory.conversations {
+		saved[k.String()] = v
+	}
+	s.memory.mu.Unlock()
+
+	if state != nil {
+		saved[key.String()] = *state
+	} else {
+		delete(saved, key.String())
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, data)
+}
@@ -0,0 +1,140 @@
+package tgbotapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func conversationUpdate(text string) Update {
+	update := textUpdate(1, text)
+	if strings.HasPrefix(text, "/") {
+		update = commandUpdate(text)
+	}
+	update.Message.From = &User{ID: 7}
+	return update
+}
+
+func TestConversationHandler(t *testing.T) {
+	ctx := context.Background()
+	h := NewConversationHandler(NewMemoryConversationStore())
+
+	var replies []string
+	h.Entry(CommandFilter("register"), func(ctx context.Context, bot *BotAPI, update Update, conv *Conversation) error {
+		conv.Transition("name")
+		return nil
+	})
+	h.Handle("name", func(ctx context.Context, bot *BotAPI, update Update, conv *Conversation) error {
+		conv.Set("name", update.Message.Text)
+		conv.Transition("age")
+		return nil
+	})
+	h.Handle("age", func(ctx context.Context, bot *BotAPI, update Update, conv *Conversation) error {
+		replies = append(replies, fmt.Sprintf("%s is %s", conv.Get("name"), update.Message.Text))
+		conv.End()
+		return nil
+	})
+	h.SetCancelCommands(func(ctx context.Context, bot *BotAPI, update Update, conv *Conversation) error {
+		replies = append(replies, "canceled in "+conv.State)
+		return nil
+	}, "cancel")
+
+	require.ErrorIs(t, h.HandleUpdate(ctx, nil, conversationUpdate("hello")), ErrFallthrough)
+
+	require.NoError(t, h.HandleUpdate(ctx, nil, conversationUpdate("/register")))
+	require.NoError(t, h.HandleUpdate(ctx, nil, conversationUpdate("Alice")))
+
+	key, _ := ConversationKeyOf(conversationUpdate(""))
+	state, ok, err := h.Current(ctx, key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "age", state.State)
+	require.Equal(t, map[string]string{"name": "Alice"}, state.Data)
+
+	require.NoError(t, h.HandleUpdate(ctx, nil, conversationUpdate("30")))
+	_, ok, _ = h.Current(ctx, key)
+	require.False(t, ok)
+
+	require.NoError(t, h.HandleUpdate(ctx, nil, conversationUpdate("/register")))
+	require.NoError(t, h.HandleUpdate(ctx, nil, conversationUpdate("/cancel")))
+	_, ok, _ = h.Current(ctx, key)
+	require.False(t, ok)
+
+	require.Equal(t, []string{"Alice is 30", "canceled in name"}, replies)
+}
+
+func TestConversationHandlerTimeout(t *testing.T) {
+	ctx := context.Background()
+	h := NewConversationHandler(NewMemoryConversationStore())
+
+	timedOut := false
+	h.SetTimeout(time.Minute, func(ctx context.Context, bot *BotAPI, update Update, conv *Conversation) error {
+		timedOut = true
+		return nil
+	})
+	h.Handle("name", func(ctx context.Context, bot *BotAPI, update Update, conv *Conversation) error {
+		t.Fatal("expired state was handled")
+		return nil
+	})
+
+	key, _ := ConversationKeyOf(conversationUpdate(""))
+	require.NoError(t, h.store.Set(ctx, key, ConversationState{State: "name", UpdatedAt: time.Now().Add(-time.Hour)}))
+
+	require.ErrorIs(t, h.HandleUpdate(ctx, nil, conversationUpdate("Alice")), ErrFallthrough)
+	require.True(t, timedOut)
+}
+
+func TestFileConversationStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "conversations.json")
+
+	store, err := NewFileConversationStore(path)
+	require.NoError(t, err)
+
+	key := ConversationKey{ChatID: -100, UserID: 7}
+	require.NoError(t, store.Set(ctx, key, ConversationState{State: "name", Data: map[string]string{"a": "b"}}))
+	require.NoError(t, store.Set(ctx, ConversationKey{ChatID: 1, UserID: 1}, ConversationState{State: "other"}))
+	require.NoError(t, store.Delete(ctx, ConversationKey{ChatID: 1, UserID: 1}))
+
+	store, err = NewFileConversationStore(path)
+	require.NoError(t, err)
+
+	state, ok, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "name", state.State)
+	require.Equal(t, map[string]string{"a": "b"}, state.Data)
+
+	_, ok, _ = store.Get(ctx, ConversationKey{ChatID: 1, UserID: 1})
+	require.False(t, ok)
+}
+
+func TestFileConversationStoreSaveError(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "conversations")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	store, err := NewFileConversationStore(filepath.Join(dir, "conversations.json"))
+	require.NoError(t, err)
+
+	key := ConversationKey{ChatID: 1, UserID: 7}
+	require.NoError(t, store.Set(ctx, key, ConversationState{State: "name"}))
+
+	// Without the directory, the file cannot be written.
+	require.NoError(t, os.RemoveAll(dir))
+
+	require.Error(t, store.Set(ctx, key, ConversationState{State: "age"}))
+	state, ok, _ := store.Get(ctx, key)
+	require.True(t, ok)
+	require.Equal(t, "name", state.State)
+
+	require.Error(t, store.Delete(ctx, key))
+	_, ok, _ = store.Get(ctx, key)
+	require.True(t, ok)
+}
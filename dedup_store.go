@@ -0,0 +1,99 @@
+package tgbotapi
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DedupStore records the IDs of received updates, to detect updates that
+// Telegram sends again. A shared implementation, such as one backed by
+// Redis, deduplicates updates across replicas.
+type DedupStore interface {
+	// MarkSeen records updateID, and returns true if it was already
+	// recorded.
+	MarkSeen(ctx context.Context, updateID int) (bool, error)
+	// Forget removes updateID, so it is accepted again. It is used when an
+	// update could not be processed.
+	Forget(ctx context.Context, updateID int) error
+}
+
+// MemoryDedupStore is a DedupStore that remembers a limited number of
+// update IDs in memory, each for a limited time.
+type MemoryDedupStore struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[int]*list.Element
+}
+
+type dedupEntry struct {
+	updateID int
+	expires  time.Time
+}
+
+// NewMemoryDedupStore creates a MemoryDedupStore remembering up to size
+// update IDs for ttl. The least recently received IDs are forgotten first.
+// The size defaults to 1000 and the ttl to an hour.
+func NewMemoryDedupStore(size int, ttl time.Duration) *MemoryDedupStore {
+	if size <= 0 {
+		size = 1000
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+
+	return &MemoryDedupStore{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[int]*list.Element),
+	}
+}
+
+// MarkSeen records updateID, and returns true if it was already recorded.
+func (s *MemoryDedupStore) MarkSeen(ctx context.Context, updateID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	if e, ok := s.entries[updateID]; ok {
+		entry := e.Value.(*dedupEntry)
+		if now.Before(entry.expires) {
+			return true, nil
+		}
+
+		s.order.Remove(e)
+		delete(s.entries, updateID)
+	}
+
+	s.entries[updateID] = s.order.PushFront(&dedupEntry{
+		updateID: updateID,
+		expires:  now.Add(s.ttl),
+	})
+
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*dedupEntry).updateID)
+	}
+
+	return false, nil
+}
+
+// Forget removes updateID.
+func (s *MemoryDedupStore) Forget(ctx context.Context, updateID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[updateID]; ok {
+		s.order.Remove(e)
+		delete(s.entries, updateID)
+	}
+
+	return nil
+}
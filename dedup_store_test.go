@@ -0,0 +1,100 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDedupStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedupStore(2, time.Hour)
+
+	seen, err := store.MarkSeen(ctx, 1)
+	require.NoError(t, err)
+	require.False(t, seen)
+
+	seen, _ = store.MarkSeen(ctx, 1)
+	require.True(t, seen)
+
+	store.MarkSeen(ctx, 2)
+	store.MarkSeen(ctx, 3)
+
+	// 1 is the oldest of three, so it was evicted.
+	seen, _ = store.MarkSeen(ctx, 1)
+	require.False(t, seen)
+
+	require.NoError(t, store.Forget(ctx, 3))
+	seen, _ = store.MarkSeen(ctx, 3)
+	require.False(t, seen)
+}
+
+func TestMemoryDedupStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedupStore(10, 10*time.Millisecond)
+
+	store.MarkSeen(ctx, 1)
+	time.Sleep(20 * time.Millisecond)
+
+	seen, _ := store.MarkSeen(ctx, 1)
+	require.False(t, seen)
+}
+
+func TestMemoryDedupStoreDefaults(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedupStore(0, 0)
+
+	seen, err := store.MarkSeen(ctx, 1)
+	require.NoError(t, err)
+	require.False(t, seen)
+
+	seen, _ = store.MarkSeen(ctx, 1)
+	require.True(t, seen)
+}
+
+func TestWebhookHandlerDedup(t *testing.T) {
+	h := NewWebhookHandler(NewBot(NewDefaultBotConfig(TestToken)))
+	h.SetBufferSize(1)
+	h.SetOverflowPolicy(OverflowReject)
+	h.SetDedupStore(NewMemoryDedupStore(100, time.Hour))
+
+	require.Equal(t, http.StatusOK, serveWebhook(h, 1))
+	require.Equal(t, http.StatusOK, serveWebhook(h, 1))
+
+	// A rejected update is accepted when it is sent again.
+	require.Equal(t, http.StatusServiceUnavailable, serveWebhook(h, 2))
+	require.Equal(t, 1, (<-h.Updates()).UpdateID)
+	require.Equal(t, http.StatusOK, serveWebhook(h, 2))
+	require.Equal(t, 2, (<-h.Updates()).UpdateID)
+
+	select {
+	case update := <-h.Updates():
+		t.Fatalf("unexpected update %d", update.UpdateID)
+	default:
+	}
+}
+
+func TestWebhookHandlerReplyHandlerDedup(t *testing.T) {
+	h := NewWebhookHandler(NewBot(NewDefaultBotConfig(TestToken)))
+	h.SetDedupStore(NewMemoryDedupStore(16, time.Minute))
+
+	var calls int
+	reply := h.ReplyHandler(func(ctx context.Context, bot *BotAPI, update Update) (Chattable, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("failed")
+		}
+		return nil, nil
+	})
+
+	// A failed update is handled again when Telegram resends it, but only
+	// once it succeeded.
+	require.Equal(t, http.StatusInternalServerError, serveWebhook(reply, 1))
+	require.Equal(t, http.StatusOK, serveWebhook(reply, 1))
+	require.Equal(t, http.StatusOK, serveWebhook(reply, 1))
+	require.Equal(t, 2, calls)
+}
@@ -0,0 +1,462 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Handler responds to an update.
+type Handler interface {
+	HandleUpdate(ctx context.Context, bot *BotAPI, update Update) error
+}
+
+// HandlerFunc is an adapter to allow the use of ordinary functions as a
+// Handler.
+type HandlerFunc func(ctx context.Context, bot *BotAPI, update Update) error
+
+// HandleUpdate calls f(ctx, bot, update).
+func (f HandlerFunc) HandleUpdate(ctx context.Context, bot *BotAPI, update Update) error {
+	return f(ctx, bot, update)
+}
+
+// ErrorHandlerFunc is called with errors returned by handlers.
+type ErrorHandlerFunc func(ctx context.Context, update Update, err error)
+
+// Filter reports whether a handler should process an update.
+type Filter func(update Update) bool
+
+var (
+	// ErrFallthrough can be returned by a handler to pass the update on to
+	// the next matching handler of the same group.
+	ErrFallthrough = errors.New("fallthrough")
+	// ErrStopPropagation can be returned by a handler to stop the update
+	// from reaching handlers in the following groups.
+	ErrStopPropagation = errors.New("stop propagation")
+)
+
+// handleUpdate passes update to handler. As no handler is left to pass the
+// update on to, ErrFallthrough and ErrStopPropagation are not errors here.
+func handleUpdate(ctx context.Context, handler Handler, bot *BotAPI, update Update) error {
+	err := handler.HandleUpdate(ctx, bot, update)
+	if errors.Is(err, ErrFallthrough) || errors.Is(err, ErrStopPropagation) {
+		return nil
+	}
+
+	return err
+}
+
+type route struct {
+	handler Handler
+	filters []Filter
+}
+
+func (r route) matches(update Update) bool {
+	for _, filter := range r.filters {
+		if !filter(update) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HandlerGroup is a set of handlers of which at most one processes an update,
+// unless a handler returns ErrFallthrough.
+type HandlerGroup struct {
+	priority int
+
+	mu     sync.RWMutex
+	routes []route
+}
+
+// Handle registers a handler that processes updates matching all filters.
+// Handlers are tried in the order they were registered.
+func (g *HandlerGroup) Handle(handler Handler, filters ...Filter) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.routes = append(g.routes, route{handler: handler, filters: filters})
+}
+
+// HandleFunc registers a handler function that processes updates matching
+// all filters.
+func (g *HandlerGroup) HandleFunc(fn HandlerFunc, filters ...Filter) {
+	g.Handle(fn, filters...)
+}
+
+// HandleUpdate passes the update to the first handler whose filters match.
+func (g *HandlerGroup) HandleUpdate(ctx context.Context, bot *BotAPI, update Update) error {
+	g.mu.RLock()
+	routes := g.routes
+	g.mu.RUnlock()
+
+	for _, r := range routes {
+		if !r.matches(update) {
+			continue
+		}
+
+		err := r.handler.HandleUpdate(ctx, bot, update)
+		if errors.Is(err, ErrFallthrough) {
+			continue
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// handleTyped registers fn for updates where get returns a non-nil value.
+func handleTyped[T any](g *HandlerGroup, get func(Update) *T, fn func(context.Context, *BotAPI, *T) error, filters []Filter) {
+	has := func(update Update) bool {
+		return get(update) != nil
+	}
+
+	g.Handle(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		return fn(ctx, bot, get(update))
+	}), append([]Filter{has}, filters...)...)
+}
+
+// OnMessage registers a handler for new messages.
+func (g *HandlerGroup) OnMessage(fn func(ctx context.Context, bot *BotAPI, message *Message) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *Message { return u.Message }, fn, filters)
+}
+
+// OnEditedMessage registers a handler for edited messages.
+func (g *HandlerGroup) OnEditedMessage(fn func(ctx context.Context, bot *BotAPI, message *Message) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *Message { return u.EditedMessage }, fn, filters)
+}
+
+// OnChannelPost registers a handler for new channel posts.
+func (g *HandlerGroup) OnChannelPost(fn func(ctx context.Context, bot *BotAPI, message *Message) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *Message { return u.ChannelPost }, fn, filters)
+}
+
+// OnEditedChannelPost registers a handler for edited channel posts.
+func (g *HandlerGroup) OnEditedChannelPost(fn func(ctx context.Context, bot *BotAPI, message *Message) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *Message { return u.EditedChannelPost }, fn, filters)
+}
+
+// OnBusinessConnection registers a handler for business connection changes.
+func (g *HandlerGroup) OnBusinessConnection(fn func(ctx context.Context, bot *BotAPI, connection *BusinessConnection) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *BusinessConnection { return u.BusinessConnection }, fn, filters)
+}
+
+// OnBusinessMessage registers a handler for messages from connected business
+// accounts.
+func (g *HandlerGroup) OnBusinessMessage(fn func(ctx context.Context, bot *BotAPI, message *Message) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *Message { return u.BusinessMessage }, fn, filters)
+}
+
+// OnEditedBusinessMessage registers a handler for edited messages from
+// connected business accounts.
+func (g *HandlerGroup) OnEditedBusinessMessage(fn func(ctx context.Context, bot *BotAPI, message *Message) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *Message { return u.EditedBusinessMessage }, fn, filters)
+}
+
+// OnDeletedBusinessMessages registers a handler for messages deleted from
+// connected business accounts.
+func (g *HandlerGroup) OnDeletedBusinessMessages(fn func(ctx context.Context, bot *BotAPI, deleted *BusinessMessagesDeleted) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *BusinessMessagesDeleted { return u.DeletedBusinessMessages }, fn, filters)
+}
+
+// OnMessageReaction registers a handler for reaction changes by users.
+func (g *HandlerGroup) OnMessageReaction(fn func(ctx context.Context, bot *BotAPI, reaction *MessageReactionUpdated) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *MessageReactionUpdated { return u.MessageReaction }, fn, filters)
+}
+
+// OnMessageReactionCount registers a handler for anonymous reaction changes.
+func (g *HandlerGroup) OnMessageReactionCount(fn func(ctx context.Context, bot *BotAPI, reactions *MessageReactionCountUpdated) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *MessageReactionCountUpdated { return u.MessageReactionCount }, fn, filters)
+}
+
+// OnInlineQuery registers a handler for inline queries.
+func (g *HandlerGroup) OnInlineQuery(fn func(ctx context.Context, bot *BotAPI, query *InlineQuery) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *InlineQuery { return u.InlineQuery }, fn, filters)
+}
+
+// OnChosenInlineResult registers a handler for chosen inline results.
+func (g *HandlerGroup) OnChosenInlineResult(fn func(ctx context.Context, bot *BotAPI, result *ChosenInlineResult) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *ChosenInlineResult { return u.ChosenInlineResult }, fn, filters)
+}
+
+// OnCallbackQuery registers a handler for callback queries.
+func (g *HandlerGroup) OnCallbackQuery(fn func(ctx context.Context, bot *BotAPI, query *CallbackQuery) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *CallbackQuery { return u.CallbackQuery }, fn, filters)
+}
+
+// OnShippingQuery registers a handler for shipping queries.
+func (g *HandlerGroup) OnShippingQuery(fn func(ctx context.Context, bot *BotAPI, query *ShippingQuery) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *ShippingQuery { return u.ShippingQuery }, fn, filters)
+}
+
+// OnPreCheckoutQuery registers a handler for pre-checkout queries.
+func (g *HandlerGroup) OnPreCheckoutQuery(fn func(ctx context.Context, bot *BotAPI, query *PreCheckoutQuery) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *PreCheckoutQuery { return u.PreCheckoutQuery }, fn, filters)
+}
+
+// OnPoll registers a handler for poll state changes.
+func (g *HandlerGroup) OnPoll(fn func(ctx context.Context, bot *BotAPI, poll *Poll) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *Poll { return u.Poll }, fn, filters)
+}
+
+// OnPollAnswer registers a handler for answers in non-anonymous polls.
+func (g *HandlerGroup) OnPollAnswer(fn func(ctx context.Context, bot *BotAPI, answer *PollAnswer) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *PollAnswer { return u.PollAnswer }, fn, filters)
+}
+
+// OnMyChatMember registers a handler for changes of the bot's own chat
+// member status.
+func (g *HandlerGroup) OnMyChatMember(fn func(ctx context.Context, bot *BotAPI, member *ChatMemberUpdated) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *ChatMemberUpdated { return u.MyChatMember }, fn, filters)
+}
+
+// OnChatMember registers a handler for chat member status changes.
+func (g *HandlerGroup) OnChatMember(fn func(ctx context.Context, bot *BotAPI, member *ChatMemberUpdated) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *ChatMemberUpdated { return u.ChatMember }, fn, filters)
+}
+
+// OnChatJoinRequest registers a handler for requests to join a chat.
+func (g *HandlerGroup) OnChatJoinRequest(fn func(ctx context.Context, bot *BotAPI, request *ChatJoinRequest) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *ChatJoinRequest { return u.ChatJoinRequest }, fn, filters)
+}
+
+// OnChatBoost registers a handler for added or changed chat boosts.
+func (g *HandlerGroup) OnChatBoost(fn func(ctx context.Context, bot *BotAPI, boost *ChatBoostUpdated) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *ChatBoostUpdated { return u.ChatBoost }, fn, filters)
+}
+
+// OnChatBoostRemoved registers a handler for removed chat boosts.
+func (g *HandlerGroup) OnChatBoostRemoved(fn func(ctx context.Context, bot *BotAPI, boost *ChatBoostRemoved) error, filters ...Filter) {
+	handleTyped(g, func(u Update) *ChatBoostRemoved { return u.ChatBoostRemoved }, fn, filters)
+}
+
+// Dispatcher routes updates to registered handlers.
+//
+// Handlers are organized in groups. Every group gets a chance to process an
+// update, starting with the group of the highest priority. Within a group,
+// only the first handler whose filters match is called. Handlers registered
+// directly on the Dispatcher belong to the group of priority 0.
+type Dispatcher struct {
+	*HandlerGroup
+
+	mu          sync.RWMutex
+	groups      []*HandlerGroup
+	middlewares []Middleware
+	onError     ErrorHandlerFunc
+}
+
+// NewDispatcher creates a new Dispatcher without any handlers.
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{}
+	d.HandlerGroup = d.Group(0)
+
+	return d
+}
+
+// Group returns the handler group with the given priority, creating it if
+// needed. Groups with a higher priority process updates first.
+func (d *Dispatcher) Group(priority int) *HandlerGroup {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, g := range d.groups {
+		if g.priority == priority {
+			return g
+		}
+	}
+
+	// dispatch iterates the old slice without holding the lock, so the
+	// groups are sorted in a copy.
+	g := &HandlerGroup{priority: priority}
+	groups := append(append(make([]*HandlerGroup, 0, len(d.groups)+1), d.groups...), g)
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].priority > groups[j].priority
+	})
+	d.groups = groups
+
+	return g
+}
+
+// OnError sets the function called with errors returned by handlers while
+// serving updates. By default, errors are logged.
+func (d *Dispatcher) OnError(fn ErrorHandlerFunc) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onError = fn
+}
+
+// Use adds middlewares that wrap the processing of every update.
+func (d *Dispatcher) Use(middlewares ...Middleware) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.middlewares = append(d.middlewares, middlewares...)
+}
+
+// HandleUpdate passes the update through the middlewares to every group,
+// until a handler returns ErrStopPropagation or another error. Updates that
+// no handler processed, for example because a middleware returned
+// ErrFallthrough, are not an error.
+func (d *Dispatcher) HandleUpdate(ctx context.Context, bot *BotAPI, update Update) error {
+	d.mu.RLock()
+	middlewares := d.middlewares
+	d.mu.RUnlock()
+
+	return handleUpdate(ctx, Chain(HandlerFunc(d.dispatch), middlewares...), bot, update)
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, bot *BotAPI, update Update) error {
+	d.mu.RLock()
+	groups := d.groups
+	d.mu.RUnlock()
+
+	for _, g := range groups {
+		err := g.HandleUpdate(ctx, bot, update)
+		if errors.Is(err, ErrStopPropagation) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Serve dispatches updates from the channel until it is closed or ctx is done.
+func (d *Dispatcher) Serve(ctx context.Context, bot *BotAPI, updates UpdatesChannel) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+
+			if err := d.HandleUpdate(ctx, bot, update); err != nil {
+				d.handleError(ctx, update, err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) handleError(ctx context.Context, update Update, err error) {
+	d.mu.RLock()
+	onError := d.onError
+	d.mu.RUnlock()
+
+	if onError != nil {
+		onError(ctx, update, err)
+		return
+	}
+
+	log.Printf("Failed to handle update %d: %v\n", update.UpdateID, err)
+}
+
+// AllOf returns a filter matching updates that match every filter.
+func AllOf(filters ...Filter) Filter {
+	return func(update Update) bool {
+		for _, filter := range filters {
+			if !filter(update) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyOf returns a filter matching updates that match at least one filter.
+func AnyOf(filters ...Filter) Filter {
+	return func(update Update) bool {
+		for _, filter := range filters {
+			if filter(update) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns a filter matching updates that do not match filter.
+func Not(filter Filter) Filter {
+	return func(update Update) bool {
+		return !filter(update)
+	}
+}
+
+// ChatFilter returns a filter matching updates from the given chats.
+func ChatFilter(chatIDs ...int64) Filter {
+	return func(update Update) bool {
+		chat := update.FromChat()
+		if chat == nil {
+			return false
+		}
+
+		for _, id := range chatIDs {
+			if chat.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// UserFilter returns a filter matching updates sent by the given users.
+func UserFilter(userIDs ...int64) Filter {
+	return func(update Update) bool {
+		user := update.SentFrom()
+		if user == nil {
+			return false
+		}
+
+		for _, id := range userIDs {
+			if user.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PrivateChatFilter matches updates from private chats.
+func PrivateChatFilter(update Update) bool {
+	chat := update.FromChat()
+	return chat != nil && chat.IsPrivate()
+}
+
+// GroupChatFilter matches updates from groups and supergroups.
+func GroupChatFilter(update Update) bool {
+	chat := update.FromChat()
+	return chat != nil && (chat.IsGroup() || chat.IsSuperGroup())
+}
+
+// TextFilter returns a filter matching messages with exactly the given text.
+func TextFilter(text string) Filter {
+	return func(update Update) bool {
+		return update.Message != nil && update.Message.Text == text
+	}
+}
+
+// CommandFilter returns a filter matching messages with one of the given
+// commands, without the leading slash.
+func CommandFilter(commands ...string) Filter {
+	return func(update Update) bool {
+		if update.Message == nil || !update.Message.IsCommand() {
+			return false
+		}
+
+		command := update.Message.Command()
+		for _, c := range commands {
+			if strings.EqualFold(command, c) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,140 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func textUpdate(chatID int64, text string) Update {
+	return Update{Message: &Message{
+		Chat: Chat{ID: chatID, Type: "private"},
+		From: &User{ID: chatID},
+		Text: text,
+	}}
+}
+
+func TestDispatcherGroups(t *testing.T) {
+	d := NewDispatcher()
+	var calls []string
+
+	d.Group(10).OnMessage(func(ctx context.Context, bot *BotAPI, message *Message) error {
+		calls = append(calls, "log")
+		return nil
+	})
+	d.OnMessage(func(ctx context.Context, bot *BotAPI, message *Message) error {
+		calls = append(calls, "hello")
+		return nil
+	}, TextFilter("hello"))
+	d.OnMessage(func(ctx context.Context, bot *BotAPI, message *Message) error {
+		calls = append(calls, "fallback")
+		return nil
+	})
+	d.OnCallbackQuery(func(ctx context.Context, bot *BotAPI, query *CallbackQuery) error {
+		calls = append(calls, "callback")
+		return nil
+	})
+
+	require.NoError(t, d.HandleUpdate(context.Background(), nil, textUpdate(1, "hello")))
+	require.Equal(t, []string{"log", "hello"}, calls)
+
+	calls = nil
+	require.NoError(t, d.HandleUpdate(context.Background(), nil, textUpdate(1, "other")))
+	require.Equal(t, []string{"log", "fallback"}, calls)
+
+	calls = nil
+	require.NoError(t, d.HandleUpdate(context.Background(), nil, Update{CallbackQuery: &CallbackQuery{}}))
+	require.Equal(t, []string{"callback"}, calls)
+}
+
+func TestDispatcherPropagation(t *testing.T) {
+	d := NewDispatcher()
+	var calls []string
+
+	d.Group(1).HandleFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		calls = append(calls, "first")
+		return ErrFallthrough
+	})
+	d.Group(1).HandleFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		calls = append(calls, "second")
+		return ErrStopPropagation
+	}, ChatFilter(1))
+	d.HandleFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		calls = append(calls, "last")
+		return errors.New("failed")
+	})
+
+	require.NoError(t, d.HandleUpdate(context.Background(), nil, textUpdate(1, "")))
+	require.Equal(t, []string{"first", "second"}, calls)
+
+	calls = nil
+	require.EqualError(t, d.HandleUpdate(context.Background(), nil, textUpdate(2, "")), "failed")
+	require.Equal(t, []string{"first", "last"}, calls)
+}
+
+func TestDispatcherFallthroughIsNotAnError(t *testing.T) {
+	d := NewDispatcher()
+	d.Use(func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+			return ErrFallthrough
+		})
+	})
+	d.HandleFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		return errors.New("failed")
+	})
+
+	require.NoError(t, d.HandleUpdate(context.Background(), nil, textUpdate(1, "")))
+
+	// Handlers returning ErrFallthrough are not an error at the top level
+	// either.
+	h := HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		return ErrFallthrough
+	})
+	require.NoError(t, handleUpdate(context.Background(), h, nil, textUpdate(1, "")))
+}
+
+func TestDispatcherGroupWhileServing(t *testing.T) {
+	d := NewDispatcher()
+	d.HandleFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = d.HandleUpdate(context.Background(), nil, textUpdate(1, ""))
+		}
+	}()
+
+	for i := 1; i <= 100; i++ {
+		d.Group(i)
+	}
+	<-done
+}
+
+func TestDispatcherServe(t *testing.T) {
+	d := NewDispatcher()
+
+	var handled []int
+	d.HandleFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		handled = append(handled, update.UpdateID)
+		return errors.New("failed")
+	})
+
+	var failed []int
+	d.OnError(func(ctx context.Context, update Update, err error) {
+		failed = append(failed, update.UpdateID)
+	})
+
+	ch := make(chan Update, 2)
+	ch <- Update{UpdateID: 1}
+	ch <- Update{UpdateID: 2}
+	close(ch)
+
+	require.NoError(t, d.Serve(context.Background(), nil, ch))
+	require.Equal(t, []int{1, 2}, handled)
+	require.Equal(t, []int{1, 2}, failed)
+}
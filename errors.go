@@ -0,0 +1,101 @@
+package tgbotapi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Errors returned by the Telegram API. An *Error matches them with errors.Is,
+// based on its error code and description:
+//
+//	if errors.Is(err, tgbotapi.ErrBotBlocked) {
+//		// stop sending messages to this user
+//	}
+//
+// Use errors.As with an *Error to access the code and ResponseParameters.
+var (
+	ErrBotBlocked            = errors.New("bot was blocked by the user")
+	ErrChatNotFound          = errors.New("chat not found")
+	ErrMessageNotModified    = errors.New("message is not modified")
+	ErrMessageToEditNotFound = errors.New("message to edit not found")
+	ErrRateLimited           = errors.New("too many requests")
+	ErrUnauthorized          = errors.New("unauthorized")
+	ErrConflict              = errors.New("conflict")
+	ErrFileTooBig            = errors.New("file is too big")
+	ErrQueryTooOld           = errors.New("query is too old")
+)
+
+// errorKind describes how an API error is recognized. An error matches if its
+// code equals code, and its description contains one of descriptions. Without
+// descriptions, the code alone is enough.
+type errorKind struct {
+	code         int
+	descriptions []string
+}
+
+var errorKinds = map[error][]errorKind{
+	ErrBotBlocked: {
+		{code: http.StatusForbidden, descriptions: []string{"bot was blocked by the user"}},
+	},
+	ErrChatNotFound: {
+		{code: http.StatusBadRequest, descriptions: []string{"chat not found"}},
+	},
+	ErrMessageNotModified: {
+		{code: http.StatusBadRequest, descriptions: []string{"message is not modified"}},
+	},
+	ErrMessageToEditNotFound: {
+		{code: http.StatusBadRequest, descriptions: []string{"message to edit not found"}},
+	},
+	ErrRateLimited: {
+		{code: http.StatusTooManyRequests},
+	},
+	ErrUnauthorized: {
+		{code: http.StatusUnauthorized},
+	},
+	ErrConflict: {
+		{code: http.StatusConflict},
+	},
+	ErrFileTooBig: {
+		{code: http.StatusBadRequest, descriptions: []string{"file is too big"}},
+		{code: http.StatusRequestEntityTooLarge},
+	},
+	ErrQueryTooOld: {
+		{code: http.StatusBadRequest, descriptions: []string{"query is too old", "query id is invalid"}},
+	},
+}
+
+func (k errorKind) matches(e Error) bool {
+	if e.Code != k.code {
+		return false
+	}
+
+	if len(k.descriptions) == 0 {
+		return true
+	}
+
+	message := strings.ToLower(e.Message)
+	for _, description := range k.descriptions {
+		if strings.Contains(message, description) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Is reports whether the error matches target, one of the API errors such as
+// ErrBotBlocked or ErrRateLimited.
+func (e Error) Is(target error) bool {
+	if target == ErrRateLimited && e.RetryAfter > 0 {
+		return true
+	}
+
+	for _, kind := range errorKinds[target] {
+		if kind.matches(e) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package tgbotapi
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorIs(t *testing.T) {
+	tests := []struct {
+		err    *Error
+		target error
+	}{
+		{&Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}, ErrBotBlocked},
+		{&Error{Code: 400, Message: "Bad Request: chat not found"}, ErrChatNotFound},
+		{&Error{Code: 400, Message: "Bad Request: message is not modified: specified new message content and reply markup are exactly the same"}, ErrMessageNotModified},
+		{&Error{Code: 400, Message: "Bad Request: message to edit not found"}, ErrMessageToEditNotFound},
+		{&Error{Code: 429, Message: "Too Many Requests: retry after 5", ResponseParameters: ResponseParameters{RetryAfter: 5}}, ErrRateLimited},
+		{&Error{Code: 401, Message: "Unauthorized"}, ErrUnauthorized},
+		{&Error{Code: 409, Message: "Conflict: terminated by other getUpdates request"}, ErrConflict},
+		{&Error{Code: 400, Message: "Bad Request: file is too big"}, ErrFileTooBig},
+		{&Error{Code: 413, Message: "Request Entity Too Large"}, ErrFileTooBig},
+		{&Error{Code: 400, Message: "Bad Request: query is too old and response timeout expired or query ID is invalid"}, ErrQueryTooOld},
+	}
+
+	for _, test := range tests {
+		var err error = fmt.Errorf("wrapped: %w", test.err)
+		require.True(t, errors.Is(err, test.target), "%q should match %q", test.err, test.target)
+
+		for _, other := range tests {
+			if other.target != test.target {
+				require.False(t, errors.Is(err, other.target), "%q should not match %q", test.err, other.target)
+			}
+		}
+	}
+
+	var apiErr *Error
+	require.True(t, errors.As(fmt.Errorf("wrapped: %w", tests[0].err), &apiErr))
+	require.Equal(t, 403, apiErr.Code)
+}
@@ -0,0 +1,478 @@
+package tgbotapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"unicode/utf8"
+)
+
+// Limits of keyboards enforced by Telegram.
+const (
+	MaxInlineKeyboardColumns = 8
+	MaxInlineKeyboardButtons = 100
+	MaxReplyKeyboardColumns  = 12
+	MaxReplyKeyboardButtons  = 300
+
+	maxInputFieldPlaceholderLength = 64
+	maxRequestUsersQuantity        = 10
+)
+
+// ErrInvalidKeyboard is returned when a keyboard would be rejected by
+// Telegram.
+var ErrInvalidKeyboard = errors.New("invalid keyboard")
+
+func keyboardError(row, column int, err error) error {
+	return fmt.Errorf("%w: row %d, button %d: %w", ErrInvalidKeyboard, row+1, column+1, err)
+}
+
+// checkKeyboardURL checks that rawURL is absolute and uses one of schemes.
+func checkKeyboardURL(rawURL string, schemes ...string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	for _, scheme := range schemes {
+		if u.Scheme == scheme && u.Host != "" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("URL %q must use scheme %v", rawURL, schemes)
+}
+
+// checkKeyboardSize checks the number of buttons of every row and of the
+// whole keyboard.
+func checkKeyboardSize(rowLengths []int, maxColumns, maxButtons int) error {
+	var total int
+	for i, n := range rowLengths {
+		if n > maxColumns {
+			return fmt.Errorf("%w: row %d has %d buttons, at most %d are allowed", ErrInvalidKeyboard, i+1, n, maxColumns)
+		}
+		total += n
+	}
+
+	if total > maxButtons {
+		return fmt.Errorf("%w: %d buttons, at most %d are allowed", ErrInvalidKeyboard, total, maxButtons)
+	}
+
+	return nil
+}
+
+// Validate checks the keyboard against the constraints of Telegram: the
+// number of buttons, that every button has exactly one action, the URL
+// schemes and length of callback data, and that pay and game buttons come
+// first.
+func (markup InlineKeyboardMarkup) Validate() error {
+	lengths := make([]int, len(markup.InlineKeyboard))
+	for i, row := range markup.InlineKeyboard {
+		lengths[i] = len(row)
+	}
+	if err := checkKeyboardSize(lengths, MaxInlineKeyboardColumns, MaxInlineKeyboardButtons); err != nil {
+		return err
+	}
+
+	for i, row := range markup.InlineKeyboard {
+		for j, button := range row {
+			if err := button.validate(i == 0 && j == 0); err != nil {
+				return keyboardError(i, j, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (button InlineKeyboardButton) validate(first bool) error {
+	if button.Text == "" {
+		return errors.New("text is empty")
+	}
+
+	var actions int
+	for _, set := range []bool{
+		button.URL != nil,
+		button.LoginURL != nil,
+		button.CallbackData != nil,
+		button.WebApp != nil,
+		button.SwitchInlineQuery != nil,
+		button.SwitchInlineQueryCurrentChat != nil,
+		button.SwitchInlineQueryChosenChat != nil,
+		button.CallbackGame != nil,
+		button.Pay,
+	} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return fmt.Errorf("%q must have exactly one action, has %d", button.Text, actions)
+	}
+
+	switch {
+	case button.URL != nil:
+		return checkKeyboardURL(*button.URL, "http", "https", "tg")
+	case button.LoginURL != nil:
+		return checkKeyboardURL(button.LoginURL.URL, "https")
+	case button.WebApp != nil:
+		return checkKeyboardURL(button.WebApp.URL, "https")
+	case button.CallbackData != nil:
+		if len(*button.CallbackData) > MaxCallbackDataLength {
+			return fmt.Errorf("%w: %d bytes", ErrCallbackDataTooLong, len(*button.CallbackData))
+		}
+	case button.Pay && !first:
+		return errors.New("pay button must be the first button")
+	case button.CallbackGame != nil && !first:
+		return errors.New("game button must be the first button")
+	}
+
+	return nil
+}
+
+// Validate checks the keyboard against the constraints of Telegram: the
+// number of buttons, that every button has at most one action, the URL
+// schemes, unique request IDs and the length of the placeholder.
+func (markup ReplyKeyboardMarkup) Validate() error {
+	if len(markup.Keyboard) == 0 {
+		return fmt.Errorf("%w: keyboard has no buttons", ErrInvalidKeyboard)
+	}
+
+	lengths := make([]int, len(markup.Keyboard))
+	for i, row := range markup.Keyboard {
+		lengths[i] = len(row)
+	}
+	if err := checkKeyboardSize(lengths, MaxReplyKeyboardColumns, MaxReplyKeyboardButtons); err != nil {
+		return err
+	}
+
+	if utf8.RuneCountInString(markup.InputFieldPlaceholder) > maxInputFieldPlaceholderLength {
+		return fmt.Errorf("%w: input field placeholder is longer than %d characters", ErrInvalidKeyboard, maxInputFieldPlaceholderLength)
+	}
+
+	requestIDs := make(map[int]bool)
+	for i, row := range markup.Keyboard {
+		for j, button := range row {
+			if err := button.validate(); err != nil {
+				return keyboardError(i, j, err)
+			}
+
+			id, ok := button.requestID()
+			if !ok {
+				continue
+			}
+			if requestIDs[id] {
+				return keyboardError(i, j, fmt.Errorf("request ID %d is not unique", id))
+			}
+			requestIDs[id] = true
+		}
+	}
+
+	return nil
+}
+
+func (button KeyboardButton) validate() error {
+	if button.Text == "" {
+		return errors.New("text is empty")
+	}
+
+	var actions int
+	for _, set := range []bool{
+		button.RequestUsers != nil,
+		button.RequestChat != nil,
+		button.RequestContact,
+		button.RequestLocation,
+		button.RequestPoll != nil,
+		button.WebApp != nil,
+	} {
+		if set {
+			actions++
+		}
+	}
+	if actions > 1 {
+		return fmt.Errorf("%q must have at most one action, has %d", button.Text, actions)
+	}
+
+	switch {
+	case button.RequestUsers != nil:
+		// A max quantity of 0 is omitted, and Telegram defaults it to 1.
+		if quantity := button.RequestUsers.MaxQuantity; quantity < 0 || quantity > maxRequestUsersQuantity {
+			return fmt.Errorf("max quantity must be 1 to %d, or 0 for the default, not %d", maxRequestUsersQuantity, quantity)
+		}
+	case button.RequestPoll != nil:
+		if t := button.RequestPoll.Type; t != "" && t != "quiz" && t != "regular" {
+			return fmt.Errorf("unknown poll type %q", t)
+		}
+	case button.WebApp != nil:
+		return checkKeyboardURL(button.WebApp.URL, "https")
+	}
+
+	return nil
+}
+
+// requestID returns the request ID of a request users or request chat
+// button.
+func (button KeyboardButton) requestID() (int, bool) {
+	switch {
+	case button.RequestUsers != nil:
+		return button.RequestUsers.RequestID, true
+	case button.RequestChat != nil:
+		return button.RequestChat.RequestID, true
+	}
+
+	return 0, false
+}
+
+// keyboardGrid lays out buttons in rows, starting a new row when the current
+// one has columns buttons, if columns is not 0.
+type keyboardGrid[T any] struct {
+	rows    [][]T
+	columns int
+	newRow  bool
+}
+
+func (g *keyboardGrid[T]) add(button T) {
+	last := len(g.rows) - 1
+	if last < 0 || g.newRow || (g.columns > 0 && len(g.rows[last]) >= g.columns) {
+		g.rows = append(g.rows, nil)
+		last++
+		g.newRow = false
+	}
+
+	g.rows[last] = append(g.rows[last], button)
+}
+
+func (g *keyboardGrid[T]) row() {
+	if len(g.rows) > 0 {
+		g.newRow = true
+	}
+}
+
+// copyRows returns a copy of the rows, so buttons added afterwards do not
+// change a built keyboard.
+func (g *keyboardGrid[T]) copyRows() [][]T {
+	rows := make([][]T, len(g.rows))
+	for i, row := range g.rows {
+		rows[i] = append([]T(nil), row...)
+	}
+
+	return rows
+}
+
+// InlineKeyboardBuilder builds an InlineKeyboardMarkup, laying out buttons
+// in rows and validating the result.
+type InlineKeyboardBuilder struct {
+	grid keyboardGrid[InlineKeyboardButton]
+}
+
+// NewInlineKeyboardBuilder creates an empty InlineKeyboardBuilder.
+func NewInlineKeyboardBuilder() *InlineKeyboardBuilder {
+	return &InlineKeyboardBuilder{}
+}
+
+// Columns starts a new row whenever the current one has columns buttons.
+// With 0, the default, rows only end with Row.
+func (b *InlineKeyboardBuilder) Columns(columns int) *InlineKeyboardBuilder {
+	b.grid.columns = columns
+	return b
+}
+
+// Row ends the current row, so the next button starts a new one.
+func (b *InlineKeyboardBuilder) Row() *InlineKeyboardBuilder {
+	b.grid.row()
+	return b
+}
+
+// If calls fn with the builder if cond is true, to add buttons only in
+// some cases.
+func (b *InlineKeyboardBuilder) If(cond bool, fn func(b *InlineKeyboardBuilder)) *InlineKeyboardBuilder {
+	if cond {
+		fn(b)
+	}
+	return b
+}
+
+// Button adds button.
+func (b *InlineKeyboardBuilder) Button(button InlineKeyboardButton) *InlineKeyboardBuilder {
+	b.grid.add(button)
+	return b
+}
+
+// Data adds a button sending a callback query with data.
+func (b *InlineKeyboardBuilder) Data(text, data string) *InlineKeyboardBuilder {
+	return b.Button(NewInlineKeyboardButtonData(text, data))
+}
+
+// URL adds a button opening rawURL.
+func (b *InlineKeyboardBuilder) URL(text, rawURL string) *InlineKeyboardBuilder {
+	return b.Button(NewInlineKeyboardButtonURL(text, rawURL))
+}
+
+// LoginURL adds a button authorizing the user on a website.
+func (b *InlineKeyboardBuilder) LoginURL(text string, loginURL LoginURL) *InlineKeyboardBuilder {
+	return b.Button(NewInlineKeyboardButtonLoginURL(text, loginURL))
+}
+
+// WebApp adds a button opening the Web App at rawURL.
+func (b *InlineKeyboardBuilder) WebApp(text, rawURL string) *InlineKeyboardBuilder {
+	return b.Button(NewInlineKeyboardButtonWebApp(text, WebAppInfo{URL: rawURL}))
+}
+
+// SwitchInlineQuery adds a button inserting the bot's username and query
+// in a chat chosen by the user.
+func (b *InlineKeyboardBuilder) SwitchInlineQuery(text, query string) *InlineKeyboardBuilder {
+	return b.Button(NewInlineKeyboardButtonSwitch(text, query))
+}
+
+// SwitchInlineQueryCurrentChat adds a button inserting the bot's username
+// and query in the current chat.
+func (b *InlineKeyboardBuilder) SwitchInlineQueryCurrentChat(text, query string) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{
+		Text:                         text,
+		SwitchInlineQueryCurrentChat: &query,
+	})
+}
+
+// Pay adds a pay button. It must be the first button of an invoice.
+func (b *InlineKeyboardBuilder) Pay(text string) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{Text: text, Pay: true})
+}
+
+// Game adds a button launching a game. It must be the first button of a
+// game message.
+func (b *InlineKeyboardBuilder) Game(text string) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{Text: text, CallbackGame: &CallbackGame{}})
+}
+
+// Build returns the keyboard, or an error wrapping ErrInvalidKeyboard if
+// Telegram would reject it.
+func (b *InlineKeyboardBuilder) Build() (InlineKeyboardMarkup, error) {
+	markup := NewInlineKeyboardMarkup(b.grid.copyRows()...)
+	if err := markup.Validate(); err != nil {
+		return InlineKeyboardMarkup{}, err
+	}
+
+	return markup, nil
+}
+
+// ReplyKeyboardBuilder builds a ReplyKeyboardMarkup, laying out buttons in
+// rows and validating the result. Like NewReplyKeyboard, it resizes the
+// keyboard by default.
+type ReplyKeyboardBuilder struct {
+	grid   keyboardGrid[KeyboardButton]
+	markup ReplyKeyboardMarkup
+}
+
+// NewReplyKeyboardBuilder creates an empty ReplyKeyboardBuilder.
+func NewReplyKeyboardBuilder() *ReplyKeyboardBuilder {
+	return &ReplyKeyboardBuilder{
+		markup: ReplyKeyboardMarkup{ResizeKeyboard: true},
+	}
+}
+
+// Columns starts a new row whenever the current one has columns buttons.
+// With 0, the default, rows only end with Row.
+func (b *ReplyKeyboardBuilder) Columns(columns int) *ReplyKeyboardBuilder {
+	b.grid.columns = columns
+	return b
+}
+
+// Row ends the current row, so the next button starts a new one.
+func (b *ReplyKeyboardBuilder) Row() *ReplyKeyboardBuilder {
+	b.grid.row()
+	return b
+}
+
+// If calls fn with the builder if cond is true, to add buttons only in
+// some cases.
+func (b *ReplyKeyboardBuilder) If(cond bool, fn func(b *ReplyKeyboardBuilder)) *ReplyKeyboardBuilder {
+	if cond {
+		fn(b)
+	}
+	return b
+}
+
+// Button adds button.
+func (b *ReplyKeyboardBuilder) Button(button KeyboardButton) *ReplyKeyboardBuilder {
+	b.grid.add(button)
+	return b
+}
+
+// Text adds a button sending text.
+func (b *ReplyKeyboardBuilder) Text(text string) *ReplyKeyboardBuilder {
+	return b.Button(NewKeyboardButton(text))
+}
+
+// Contact adds a button sending the user's phone number.
+func (b *ReplyKeyboardBuilder) Contact(text string) *ReplyKeyboardBuilder {
+	return b.Button(NewKeyboardButtonContact(text))
+}
+
+// Location adds a button sending the user's location.
+func (b *ReplyKeyboardBuilder) Location(text string) *ReplyKeyboardBuilder {
+	return b.Button(NewKeyboardButtonLocation(text))
+}
+
+// RequestUsers adds a button asking the user to pick users.
+func (b *ReplyKeyboardBuilder) RequestUsers(text string, request KeyboardButtonRequestUsers) *ReplyKeyboardBuilder {
+	return b.Button(KeyboardButton{Text: text, RequestUsers: &request})
+}
+
+// RequestChat adds a button asking the user to pick a chat.
+func (b *ReplyKeyboardBuilder) RequestChat(text string, request KeyboardButtonRequestChat) *ReplyKeyboardBuilder {
+	return b.Button(KeyboardButton{Text: text, RequestChat: &request})
+}
+
+// Poll adds a button asking the user to create a poll of pollType, which is
+// "quiz", "regular" or empty for any type.
+func (b *ReplyKeyboardBuilder) Poll(text, pollType string) *ReplyKeyboardBuilder {
+	return b.Button(KeyboardButton{Text: text, RequestPoll: &KeyboardButtonPollType{Type: pollType}})
+}
+
+// WebApp adds a button opening the Web App at rawURL.
+func (b *ReplyKeyboardBuilder) WebApp(text, rawURL string) *ReplyKeyboardBuilder {
+	return b.Button(NewKeyboardButtonWebApp(text, WebAppInfo{URL: rawURL}))
+}
+
+// Resize sets whether the keyboard is resized to fit its buttons.
+func (b *ReplyKeyboardBuilder) Resize(resize bool) *ReplyKeyboardBuilder {
+	b.markup.ResizeKeyboard = resize
+	return b
+}
+
+// OneTime hides the keyboard after it was used.
+func (b *ReplyKeyboardBuilder) OneTime() *ReplyKeyboardBuilder {
+	b.markup.OneTimeKeyboard = true
+	return b
+}
+
+// Persistent always shows the keyboard when the regular keyboard is hidden.
+func (b *ReplyKeyboardBuilder) Persistent() *ReplyKeyboardBuilder {
+	b.markup.IsPersistent = true
+	return b
+}
+
+// Selective shows the keyboard only to mentioned users and the sender of
+// the replied message.
+func (b *ReplyKeyboardBuilder) Selective() *ReplyKeyboardBuilder {
+	b.markup.Selective = true
+	return b
+}
+
+// Placeholder sets the placeholder of the input field while the keyboard is
+// shown.
+func (b *ReplyKeyboardBuilder) Placeholder(placeholder string) *ReplyKeyboardBuilder {
+	b.markup.InputFieldPlaceholder = placeholder
+	return b
+}
+
+// Build returns the keyboard, or an error wrapping ErrInvalidKeyboard if
+// Telegram would reject it.
+func (b *ReplyKeyboardBuilder) Build() (ReplyKeyboardMarkup, error) {
+	markup := b.markup
+	markup.Keyboard = b.grid.copyRows()
+	if err := markup.Validate(); err != nil {
+		return ReplyKeyboardMarkup{}, err
+	}
+
+	return markup, nil
+}
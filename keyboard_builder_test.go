@@ -0,0 +1,105 @@
+package tgbotapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineKeyboardBuilder(t *testing.T) {
+	admin := false
+	markup, err := NewInlineKeyboardBuilder().
+		Columns(2).
+		Data("1", "a").Data("2", "b").Data("3", "c").
+		Row().
+		URL("Site", "https://example.com").
+		If(admin, func(b *InlineKeyboardBuilder) {
+			b.Data("Delete", "delete")
+		}).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1", "2"}, {"3"}, {"Site"}}, buttonTexts(markup))
+
+	markup, err = NewInlineKeyboardBuilder().Pay("Pay").Data("Cancel", "cancel").Build()
+	require.NoError(t, err)
+	require.True(t, markup.InlineKeyboard[0][0].Pay)
+
+	// A built keyboard does not share its rows with the builder.
+	builder := NewInlineKeyboardBuilder().Data("1", "a")
+	first, err := builder.Build()
+	require.NoError(t, err)
+	first.InlineKeyboard[0][0].Text = "changed"
+	second, err := builder.Data("2", "b").Build()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1", "2"}}, buttonTexts(second))
+}
+
+func TestInlineKeyboardValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *InlineKeyboardBuilder
+	}{
+		{"no action", NewInlineKeyboardBuilder().Button(InlineKeyboardButton{Text: "x"})},
+		{"two actions", NewInlineKeyboardBuilder().Button(InlineKeyboardButton{Text: "x", Pay: true, URL: new(string)})},
+		{"empty text", NewInlineKeyboardBuilder().Data("", "a")},
+		{"url scheme", NewInlineKeyboardBuilder().URL("x", "ftp://example.com")},
+		{"web app scheme", NewInlineKeyboardBuilder().WebApp("x", "http://example.com")},
+		{"pay not first", NewInlineKeyboardBuilder().Data("x", "a").Pay("Pay")},
+		{"too many columns", NewInlineKeyboardBuilder().Data("1", "1").Data("2", "2").Data("3", "3").Data("4", "4").Data("5", "5").Data("6", "6").Data("7", "7").Data("8", "8").Data("9", "9")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.builder.Build()
+			require.ErrorIs(t, err, ErrInvalidKeyboard)
+		})
+	}
+
+	_, err := NewInlineKeyboardBuilder().Data("x", strings.Repeat("a", 65)).Build()
+	require.ErrorIs(t, err, ErrCallbackDataTooLong)
+
+	_, err = NewInlineKeyboardBuilder().URL("x", "tg://user?id=1").Build()
+	require.NoError(t, err)
+}
+
+func TestReplyKeyboardBuilder(t *testing.T) {
+	markup, err := NewReplyKeyboardBuilder().
+		Columns(2).
+		Text("Yes").Text("No").
+		Contact("Phone").
+		Row().
+		RequestUsers("Users", KeyboardButtonRequestUsers{RequestID: 1, MaxQuantity: 3}).
+		RequestChat("Chat", KeyboardButtonRequestChat{RequestID: 2}).
+		Poll("Quiz", "quiz").
+		OneTime().
+		Placeholder("Choose").
+		Build()
+	require.NoError(t, err)
+	require.Len(t, markup.Keyboard, 4)
+	require.Equal(t, "Quiz", markup.Keyboard[3][0].Text)
+	require.True(t, markup.ResizeKeyboard)
+	require.True(t, markup.OneTimeKeyboard)
+	require.Equal(t, "Choose", markup.InputFieldPlaceholder)
+
+	tests := []struct {
+		name    string
+		builder *ReplyKeyboardBuilder
+	}{
+		{"empty", NewReplyKeyboardBuilder()},
+		{"two actions", NewReplyKeyboardBuilder().Button(KeyboardButton{Text: "x", RequestContact: true, RequestLocation: true})},
+		{"poll type", NewReplyKeyboardBuilder().Poll("x", "survey")},
+		{"too many users", NewReplyKeyboardBuilder().RequestUsers("x", KeyboardButtonRequestUsers{MaxQuantity: 11})},
+		{"negative users", NewReplyKeyboardBuilder().RequestUsers("x", KeyboardButtonRequestUsers{MaxQuantity: -1})},
+		{"duplicate request", NewReplyKeyboardBuilder().RequestUsers("x", KeyboardButtonRequestUsers{RequestID: 1}).RequestChat("y", KeyboardButtonRequestChat{RequestID: 1})},
+		{"web app scheme", NewReplyKeyboardBuilder().WebApp("x", "http://example.com")},
+		{"placeholder", NewReplyKeyboardBuilder().Text("x").Placeholder(strings.Repeat("a", 65))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.builder.Build()
+			require.ErrorIs(t, err, ErrInvalidKeyboard)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package tgbotapi
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Middleware wraps a Handler to run code before or after it.
+type Middleware func(next Handler) Handler
+
+// Chain wraps handler with middlewares. The first middleware is the
+// outermost one, so it runs first.
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// LoggingMiddleware logs every update with its processing time and error.
+func LoggingMiddleware(logger BotLogger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+			start := time.Now()
+			err := next.HandleUpdate(ctx, bot, update)
+
+			if err != nil {
+				logger.Printf("Update %d (%s) failed after %s: %v\n", update.UpdateID, update.Type(), time.Since(start), err)
+			} else {
+				logger.Printf("Update %d (%s) handled in %s\n", update.UpdateID, update.Type(), time.Since(start))
+			}
+
+			return err
+		})
+	}
+}
+
+// PanicError is returned by RecoverMiddleware when a handler panics.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.Value, e.Stack)
+}
+
+// RecoverMiddleware recovers from panics in handlers and returns them as a
+// *PanicError, so a single bad update does not take down the process.
+func RecoverMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+
+			return next.HandleUpdate(ctx, bot, update)
+		})
+	}
+}
+
+// ThrottleMiddleware limits how many updates of a single user are handled.
+// Updates over the limit are passed to onThrottled, or dropped if it is nil.
+// Updates without a sender are not limited.
+func ThrottleMiddleware(limit Limit, onThrottled Handler) Middleware {
+	var (
+		mu      sync.Mutex
+		buckets = make(map[int64]*tokenBucket)
+	)
+
+	allow := func(userID int64) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		bucket, ok := buckets[userID]
+		if !ok {
+			if len(buckets) >= chatBucketsSweepSize {
+				for id, b := range buckets {
+					if b.full(now) {
+						delete(buckets, id)
+					}
+				}
+			}
+
+			bucket = newTokenBucket(limit, now)
+			buckets[userID] = bucket
+		}
+
+		if bucket.wait(now) > 0 {
+			return false
+		}
+
+		bucket.take()
+		return true
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+			user := update.SentFrom()
+			if user == nil || allow(user.ID) {
+				return next.HandleUpdate(ctx, bot, update)
+			}
+
+			if onThrottled != nil {
+				return onThrottled.HandleUpdate(ctx, bot, update)
+			}
+
+			return nil
+		})
+	}
+}
+
+// AdminOnlyMiddleware only lets updates through if they were sent in a group
+// by an administrator or the creator of the group, as reported by
+// getChatMember. Other updates are passed on with ErrFallthrough, so the
+// next handler of the group can process them.
+func AdminOnlyMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+			chat := update.FromChat()
+			user := update.SentFrom()
+			if chat == nil || user == nil || chat.IsPrivate() {
+				return ErrFallthrough
+			}
+
+			member, err := bot.GetChatMemberContext(ctx, GetChatMemberConfig{
+				ChatConfigWithUser: ChatConfigWithUser{
+					ChatConfig: chat.ChatConfig(),
+					UserID:     user.ID,
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			if !member.IsAdministrator() && !member.IsCreator() {
+				return ErrFallthrough
+			}
+
+			return next.HandleUpdate(ctx, bot, update)
+		})
+	}
+}
+
+// MetricsMiddleware calls observe after every update with the update type,
+// the processing time and the returned error.
+func MetricsMiddleware(observe func(updateType string, duration time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+			start := time.Now()
+			err := next.HandleUpdate(ctx, bot, update)
+			observe(update.Type(), time.Since(start), err)
+
+			return err
+		})
+	}
+}
+
+// TracingMiddleware calls start before every update. The returned context is
+// passed to the handler, and end is called with the handler's error. It can
+// be used to start a span with any tracing library.
+func TracingMiddleware(start func(ctx context.Context, update Update) (context.Context, func(err error))) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+			ctx, end := start(ctx, update)
+			err := next.HandleUpdate(ctx, bot, update)
+			end(err)
+
+			return err
+		})
+	}
+}
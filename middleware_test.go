@@ -0,0 +1,86 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+				calls = append(calls, name)
+				return next.HandleUpdate(ctx, bot, update)
+			})
+		}
+	}
+
+	d := NewDispatcher()
+	d.Use(mw("first"), mw("second"))
+	d.HandleFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+
+	require.NoError(t, d.HandleUpdate(context.Background(), nil, Update{}))
+	require.Equal(t, []string{"first", "second", "handler"}, calls)
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	handler := Chain(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		panic("boom")
+	}), RecoverMiddleware())
+
+	err := handler.HandleUpdate(context.Background(), nil, Update{})
+
+	var panicErr *PanicError
+	require.True(t, errors.As(err, &panicErr))
+	require.Equal(t, "boom", panicErr.Value)
+}
+
+func TestThrottleMiddleware(t *testing.T) {
+	var handled, throttled int
+	handler := Chain(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		handled++
+		return nil
+	}), ThrottleMiddleware(Limit{Events: 2, Interval: time.Hour}, HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		throttled++
+		return nil
+	})))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, handler.HandleUpdate(context.Background(), nil, textUpdate(1, "")))
+	}
+	require.NoError(t, handler.HandleUpdate(context.Background(), nil, textUpdate(2, "")))
+
+	require.Equal(t, 3, handled)
+	require.Equal(t, 1, throttled)
+}
+
+func TestAdminOnlyMiddleware(t *testing.T) {
+	status := "member"
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		require.True(t, isRequestValid(req, TestToken, "getChatMember"))
+		return newOKResponse(`{"ok": true, "result": {"status": "` + status + `", "user": {"id": 2}}}`), nil
+	}))
+
+	var handled int
+	handler := Chain(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		handled++
+		return nil
+	}), AdminOnlyMiddleware())
+
+	update := Update{Message: &Message{Chat: Chat{ID: -1, Type: "supergroup"}, From: &User{ID: 2}}}
+
+	require.ErrorIs(t, handler.HandleUpdate(context.Background(), bot, update), ErrFallthrough)
+	status = "administrator"
+	require.NoError(t, handler.HandleUpdate(context.Background(), bot, update))
+	require.ErrorIs(t, handler.HandleUpdate(context.Background(), bot, textUpdate(2, "")), ErrFallthrough)
+	require.Equal(t, 1, handled)
+}
@@ -0,0 +1,106 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// OffsetStore persists the offset of the next update to process, so polling
+// continues where it stopped after a restart.
+type OffsetStore interface {
+	// LoadOffset returns the saved offset, or 0 if there is none.
+	LoadOffset(ctx context.Context) (int, error)
+	// SaveOffset saves offset, replacing the previous one.
+	SaveOffset(ctx context.Context, offset int) error
+}
+
+// MemoryOffsetStore keeps the offset in memory. It is useful for tests, or
+// to share an offset between handlers in the same process.
+type MemoryOffsetStore struct {
+	mu     sync.Mutex
+	offset int
+}
+
+// NewMemoryOffsetStore creates an empty MemoryOffsetStore.
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{}
+}
+
+// LoadOffset returns the saved offset.
+func (s *MemoryOffsetStore) LoadOffset(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.offset, nil
+}
+
+// SaveOffset saves offset.
+func (s *MemoryOffsetStore) SaveOffset(ctx context.Context, offset int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offset = offset
+	return nil
+}
+
+// FileOffsetStore keeps the offset in a file. The file is replaced
+// atomically, so a crash while saving leaves the previous offset.
+type FileOffsetStore struct {
+	path string
+}
+
+// NewFileOffsetStore creates a FileOffsetStore saving to path.
+func NewFileOffsetStore(path string) *FileOffsetStore {
+	return &FileOffsetStore{
+		path: path,
+	}
+}
+
+// LoadOffset reads the offset from the file. A missing file is offset 0.
+func (s *FileOffsetStore) LoadOffset(ctx context.Context) (int, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// SaveOffset writes the offset to the file.
+func (s *FileOffsetStore) SaveOffset(ctx context.Context, offset int) error {
+	return writeFileAtomic(s.path, []byte(strconv.Itoa(offset)+"\n"))
+}
+
+// writeFileAtomic replaces the file at path with data, so a crash while
+// writing leaves the previous contents.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
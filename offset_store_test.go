@@ -0,0 +1,86 @@
+package tgbotapi
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileOffsetStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileOffsetStore(filepath.Join(t.TempDir(), "offset"))
+
+	offset, err := store.LoadOffset(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, offset)
+
+	require.NoError(t, store.SaveOffset(ctx, 42))
+	require.NoError(t, store.SaveOffset(ctx, 43))
+
+	offset, err = NewFileOffsetStore(store.path).LoadOffset(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 43, offset)
+}
+
+func TestPollingHandlerOffsetStore(t *testing.T) {
+	ctx := context.Background()
+	server := &pollingServer{updates: []int{10, 11}}
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), server)
+
+	store := NewMemoryOffsetStore()
+	h := NewPollingHandler(bot, NewUpdate(0))
+	h.SetOffsetStore(store)
+
+	updates, err := h.Start(ctx)
+	require.NoError(t, err)
+
+	update := <-updates
+	require.Equal(t, 10, update.UpdateID)
+	require.NoError(t, h.Commit(ctx, update))
+	require.Equal(t, 11, (<-updates).UpdateID)
+
+	require.NoError(t, h.Stop(ctx))
+	require.Equal(t, "11", server.lastOffset())
+
+	offset, err := store.LoadOffset(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 11, offset)
+
+	// The uncommitted update is received again by a new handler.
+	h = NewPollingHandler(bot, NewUpdate(0))
+	h.SetOffsetStore(store)
+
+	updates, err = h.Start(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 11, (<-updates).UpdateID)
+	require.NoError(t, h.Stop(ctx))
+}
+
+func TestPollingHandlerCommitOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	server := &pollingServer{updates: []int{1, 2, 3}}
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), server)
+
+	store := NewMemoryOffsetStore()
+	h := NewPollingHandler(bot, NewUpdate(0))
+	h.SetOffsetStore(store)
+
+	updates, err := h.Start(ctx)
+	require.NoError(t, err)
+	first, second, third := <-updates, <-updates, <-updates
+
+	require.NoError(t, h.Commit(ctx, third))
+	require.NoError(t, h.Commit(ctx, second))
+	offset, err := store.LoadOffset(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, offset)
+
+	require.NoError(t, h.Commit(ctx, first))
+	offset, err = store.LoadOffset(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 4, offset)
+
+	require.NoError(t, h.Stop(ctx))
+}
@@ -0,0 +1,210 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// PageFetchFunc returns up to limit items starting at offset, and the total
+// number of items.
+type PageFetchFunc[T any] func(ctx context.Context, offset, limit int) ([]T, int, error)
+
+// SliceSource returns a PageFetchFunc paging through items.
+func SliceSource[T any](items []T) PageFetchFunc[T] {
+	return func(ctx context.Context, offset, limit int) ([]T, int, error) {
+		start := min(offset, len(items))
+		end := min(offset+limit, len(items))
+
+		return items[start:end], len(items), nil
+	}
+}
+
+// ItemRenderer returns the button showing item.
+type ItemRenderer[T any] func(ctx context.Context, item T) (InlineKeyboardButton, error)
+
+// PageTextFunc returns the message text of a page. page is zero-based.
+type PageTextFunc[T any] func(items []T, page, pages int) string
+
+type paginatorPage struct {
+	Page int
+}
+
+// Paginator shows items page by page in an inline keyboard, with buttons to
+// move between pages. Navigation callbacks are handled by the CallbackCodec
+// the paginator was created with, which edits the message in place.
+type Paginator[T any] struct {
+	codec    *CallbackCodec
+	action   string
+	pageSize int
+	fetch    PageFetchFunc[T]
+	render   ItemRenderer[T]
+	text     PageTextFunc[T]
+}
+
+// NewPaginator creates a Paginator showing pageSize items per page, fetched
+// by fetch and shown by render, and registers its navigation callbacks with
+// codec under action. action must be unique among the codec's actions.
+func NewPaginator[T any](codec *CallbackCodec, action string, pageSize int, fetch PageFetchFunc[T], render ItemRenderer[T]) *Paginator[T] {
+	p := &Paginator[T]{
+		codec:    codec,
+		action:   action,
+		pageSize: max(pageSize, 1),
+		fetch:    fetch,
+		render:   render,
+	}
+
+	HandleCallback(codec, action, p.navigate)
+
+	return p
+}
+
+// SetText sets the function returning the message text of a page. Without
+// it, navigation only edits the keyboard and Message can't be used.
+func (p *Paginator[T]) SetText(text PageTextFunc[T]) {
+	p.text = text
+}
+
+// Keyboard returns the keyboard of page, which is zero-based and clamped to
+// the existing pages.
+func (p *Paginator[T]) Keyboard(ctx context.Context, page int) (InlineKeyboardMarkup, error) {
+	markup, _, err := p.page(ctx, page)
+	return markup, err
+}
+
+// Message returns a message showing page in chatID.
+func (p *Paginator[T]) Message(ctx context.Context, chatID int64, page int) (MessageConfig, error) {
+	if p.text == nil {
+		return MessageConfig{}, errors.New("paginator has no text function")
+	}
+
+	markup, text, err := p.page(ctx, page)
+	if err != nil {
+		return MessageConfig{}, err
+	}
+
+	msg := NewMessage(chatID, text)
+	msg.ReplyMarkup = markup
+
+	return msg, nil
+}
+
+// page fetches page and returns its keyboard and text.
+func (p *Paginator[T]) page(ctx context.Context, page int) (InlineKeyboardMarkup, string, error) {
+	page = max(page, 0)
+
+	items, total, err := p.fetch(ctx, page*p.pageSize, p.pageSize)
+	if err != nil {
+		return InlineKeyboardMarkup{}, "", err
+	}
+
+	pages := max((total+p.pageSize-1)/p.pageSize, 1)
+	if page >= pages {
+		page = pages - 1
+		items, total, err = p.fetch(ctx, page*p.pageSize, p.pageSize)
+		if err != nil {
+			return InlineKeyboardMarkup{}, "", err
+		}
+	}
+
+	rows := make([][]InlineKeyboardButton, 0, len(items)+1)
+	for _, item := range items {
+		button, err := p.render(ctx, item)
+		if err != nil {
+			return InlineKeyboardMarkup{}, "", err
+		}
+		rows = append(rows, NewInlineKeyboardRow(button))
+	}
+
+	if pages > 1 {
+		nav, err := p.navigation(ctx, page, pages)
+		if err != nil {
+			return InlineKeyboardMarkup{}, "", err
+		}
+		rows = append(rows, nav)
+	}
+
+	var text string
+	if p.text != nil {
+		text = p.text(items, page, pages)
+	}
+
+	return NewInlineKeyboardMarkup(rows...), text, nil
+}
+
+// navigation returns the row with the previous and next buttons and the
+// page number, which reloads the current page.
+func (p *Paginator[T]) navigation(ctx context.Context, page, pages int) ([]InlineKeyboardButton, error) {
+	var row []InlineKeyboardButton
+
+	add := func(text string, target int) error {
+		button, err := p.codec.Button(ctx, text, p.action, paginatorPage{Page: target})
+		if err != nil {
+			return err
+		}
+		row = append(row, button)
+		return nil
+	}
+
+	if page > 0 {
+		if err := add("«", page-1); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(strconv.Itoa(page+1)+"/"+strconv.Itoa(pages), page); err != nil {
+		return nil, err
+	}
+	if page < pages-1 {
+		if err := add("»", page+1); err != nil {
+			return nil, err
+		}
+	}
+
+	return row, nil
+}
+
+// navigate shows the requested page in the message of query.
+func (p *Paginator[T]) navigate(ctx context.Context, bot *BotAPI, query *CallbackQuery, data paginatorPage) (err error) {
+	// The query is answered even if the page can't be shown, so the button
+	// stops loading.
+	defer func() {
+		if _, answerErr := bot.RequestContext(ctx, NewCallback(query.ID, "")); answerErr != nil {
+			err = errors.Join(err, answerErr)
+		}
+	}()
+
+	markup, text, err := p.page(ctx, data.Page)
+	if err != nil {
+		return err
+	}
+
+	var edit Chattable
+	if p.text != nil {
+		config := EditMessageTextConfig{Text: text}
+		config.BaseEdit = paginatorEdit(query, markup)
+		edit = config
+	} else {
+		edit = EditMessageReplyMarkupConfig{BaseEdit: paginatorEdit(query, markup)}
+	}
+
+	if _, err := bot.RequestContext(ctx, edit); err != nil && !errors.Is(err, ErrMessageNotModified) {
+		return fmt.Errorf("paginator %s: %w", p.action, err)
+	}
+
+	return nil
+}
+
+// paginatorEdit returns the BaseEdit of the message of query.
+func paginatorEdit(query *CallbackQuery, markup InlineKeyboardMarkup) BaseEdit {
+	edit := BaseEdit{
+		InlineMessageID: query.InlineMessageID,
+		ReplyMarkup:     &markup,
+	}
+	if query.Message != nil {
+		edit.ChatID = query.Message.Chat.ID
+		edit.MessageID = query.Message.MessageID
+	}
+
+	return edit
+}
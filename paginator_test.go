@@ -0,0 +1,126 @@
+package tgbotapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPaginator(codec *CallbackCodec) *Paginator[int] {
+	items := []int{1, 2, 3, 4, 5}
+
+	return NewPaginator(codec, "p", 2, SliceSource(items), func(ctx context.Context, item int) (InlineKeyboardButton, error) {
+		return NewInlineKeyboardButtonData(strconv.Itoa(item), "item|"+strconv.Itoa(item)), nil
+	})
+}
+
+func buttonTexts(markup InlineKeyboardMarkup) [][]string {
+	var texts [][]string
+	for _, row := range markup.InlineKeyboard {
+		var line []string
+		for _, button := range row {
+			line = append(line, button.Text)
+		}
+		texts = append(texts, line)
+	}
+
+	return texts
+}
+
+func TestPaginatorKeyboard(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPaginator(NewCallbackCodec())
+
+	markup, err := p.Keyboard(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1"}, {"2"}, {"1/3", "»"}}, buttonTexts(markup))
+	require.Equal(t, "p|1", *markup.InlineKeyboard[2][1].CallbackData)
+
+	markup, err = p.Keyboard(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"3"}, {"4"}, {"«", "2/3", "»"}}, buttonTexts(markup))
+
+	markup, err = p.Keyboard(ctx, 10)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"5"}, {"«", "3/3"}}, buttonTexts(markup))
+
+	_, err = p.Message(ctx, 1, 0)
+	require.Error(t, err)
+}
+
+func TestPaginatorNavigate(t *testing.T) {
+	ctx := context.Background()
+	codec := NewCallbackCodec()
+	p := newTestPaginator(codec)
+	p.SetText(func(items []int, page, pages int) string {
+		return "Page " + strconv.Itoa(page+1)
+	})
+
+	msg, err := p.Message(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, "Page 1", msg.Text)
+
+	var requests []*http.Request
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		require.NoError(t, req.ParseForm())
+		requests = append(requests, req)
+		return newOKResponse(`{"ok": true, "result": true}`), nil
+	}))
+
+	update := Update{CallbackQuery: &CallbackQuery{
+		ID:      "q",
+		Message: &Message{Chat: Chat{ID: 10}, MessageID: 20},
+		Data:    "p|2",
+	}}
+	require.NoError(t, codec.HandleUpdate(ctx, bot, update))
+
+	require.Len(t, requests, 2)
+	require.True(t, isRequestValid(requests[0], TestToken, "editMessageText"))
+	require.Equal(t, "Page 3", requests[0].Form.Get("text"))
+	require.Equal(t, "10", requests[0].Form.Get("chat_id"))
+	require.Equal(t, "20", requests[0].Form.Get("message_id"))
+	require.True(t, isRequestValid(requests[1], TestToken, "answerCallbackQuery"))
+}
+
+func TestPaginatorNavigateInline(t *testing.T) {
+	ctx := context.Background()
+	codec := NewCallbackCodec()
+	newTestPaginator(codec)
+
+	var methods []string
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		require.NoError(t, req.ParseForm())
+		methods = append(methods, req.URL.Path)
+		if isRequestValid(req, TestToken, "editMessageReplyMarkup") {
+			require.Equal(t, "inline", req.Form.Get("inline_message_id"))
+			return newErrorResponse(http.StatusBadRequest, `{"ok": false, "error_code": 400, "description": "Bad Request: message is not modified"}`), nil
+		}
+		return newOKResponse(`{"ok": true, "result": true}`), nil
+	}))
+
+	update := Update{CallbackQuery: &CallbackQuery{ID: "q", InlineMessageID: "inline", Data: "p|0"}}
+	require.NoError(t, codec.HandleUpdate(ctx, bot, update))
+	require.Len(t, methods, 2)
+}
+
+func TestPaginatorNavigateEditError(t *testing.T) {
+	ctx := context.Background()
+	codec := NewCallbackCodec()
+	newTestPaginator(codec)
+
+	var answered bool
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		if isRequestValid(req, TestToken, "answerCallbackQuery") {
+			answered = true
+			return newOKResponse(`{"ok": true, "result": true}`), nil
+		}
+		return newErrorResponse(http.StatusBadRequest, `{"ok": false, "error_code": 400, "description": "Bad Request: message to edit not found"}`), nil
+	}))
+
+	update := Update{CallbackQuery: &CallbackQuery{ID: "q", InlineMessageID: "inline", Data: "p|0"}}
+	require.Error(t, codec.HandleUpdate(ctx, bot, update))
+	require.True(t, answered)
+}
@@ -0,0 +1,164 @@
+package tgbotapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// SessionScope returns the key of the session an update belongs to, or
+// false if it has none.
+type SessionScope func(update Update) (string, bool)
+
+// UserSessionScope keys sessions by the user who sent the update.
+func UserSessionScope(update Update) (string, bool) {
+	user := update.SentFrom()
+	if user == nil {
+		return "", false
+	}
+
+	return "user:" + strconv.FormatInt(user.ID, 10), true
+}
+
+// ChatSessionScope keys sessions by the chat of the update.
+func ChatSessionScope(update Update) (string, bool) {
+	chat := update.FromChat()
+	if chat == nil {
+		return "", false
+	}
+
+	return "chat:" + strconv.FormatInt(chat.ID, 10), true
+}
+
+// maxSessionUpdateAttempts limits how often Sessions.Update retries after
+// a conflict.
+const maxSessionUpdateAttempts = 10
+
+// Sessions stores sessions of type T, encoded as JSON, in a SessionStore.
+type Sessions[T any] struct {
+	store SessionStore
+	scope SessionScope
+}
+
+// NewSessions creates Sessions saved to store, with keys from scope.
+func NewSessions[T any](store SessionStore, scope SessionScope) *Sessions[T] {
+	return &Sessions[T]{
+		store: store,
+		scope: scope,
+	}
+}
+
+// Get returns the session with the given key and its version. If there is
+// none, it returns the zero value and version 0.
+func (s *Sessions[T]) Get(ctx context.Context, key string) (T, int64, error) {
+	var value T
+
+	data, version, err := s.store.Load(ctx, key)
+	if err != nil || data == nil {
+		return value, version, err
+	}
+
+	err = json.Unmarshal(data, &value)
+	return value, version, err
+}
+
+// Save saves the session with the given key if its current version is
+// version, and returns the new version. Otherwise, it returns
+// ErrSessionConflict.
+func (s *Sessions[T]) Save(ctx context.Context, key string, value T, version int64) (int64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.store.Save(ctx, key, data, version)
+}
+
+// Delete removes the session with the given key.
+func (s *Sessions[T]) Delete(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, key)
+}
+
+// Update loads the session with the given key, passes it to fn and saves
+// it. If the session was changed concurrently, it is loaded again and fn is
+// called again, so fn should have no other effects.
+func (s *Sessions[T]) Update(ctx context.Context, key string, fn func(value *T) error) error {
+	for attempt := 0; attempt < maxSessionUpdateAttempts; attempt++ {
+		value, version, err := s.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(&value); err != nil {
+			return err
+		}
+
+		_, err = s.Save(ctx, key, value, version)
+		if !errors.Is(err, ErrSessionConflict) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("session %s: %w", key, ErrSessionConflict)
+}
+
+type sessionContextKey[T any] struct {
+	sessions *Sessions[T]
+}
+
+// FromContext returns the session loaded by the middleware, or nil if there
+// is none.
+func (s *Sessions[T]) FromContext(ctx context.Context) *T {
+	value, _ := ctx.Value(sessionContextKey[T]{s}).(*T)
+	return value
+}
+
+// Middleware returns a middleware that loads the session of every update
+// before the handler is called, and saves it afterwards if it was changed.
+// Handlers access the session with FromContext.
+//
+// If the handler returns an error, the session is not saved. If the session
+// was saved by another handler meanwhile, the middleware returns
+// ErrSessionConflict instead of overwriting it.
+func (s *Sessions[T]) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+			key, ok := s.scope(update)
+			if !ok {
+				return next.HandleUpdate(ctx, bot, update)
+			}
+
+			value, version, err := s.Get(ctx, key)
+			if err != nil {
+				return err
+			}
+
+			loaded, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+
+			ctx = context.WithValue(ctx, sessionContextKey[T]{s}, &value)
+			if err := next.HandleUpdate(ctx, bot, update); err != nil {
+				return err
+			}
+
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(data, loaded) {
+				return nil
+			}
+
+			if _, err := s.store.Save(ctx, key, data, version); err != nil {
+				return fmt.Errorf("session %s: %w", key, err)
+			}
+
+			return nil
+		})
+	}
+}
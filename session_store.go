@@ -0,0 +1,243 @@
+package tgbotapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrSessionConflict is returned when a session is saved with a version that
+// is no longer current, because it was changed concurrently.
+var ErrSessionConflict = errors.New("session was changed concurrently")
+
+// SessionStore stores encoded sessions by key. Every saved session has a
+// version, which must be passed when saving it again, so that concurrent
+// changes are detected instead of overwritten.
+type SessionStore interface {
+	// Load returns the session data and its version. If there is no
+	// session, or it expired, it returns nil data and version 0.
+	Load(ctx context.Context, key string) ([]byte, int64, error)
+	// Save stores data if the current version of the session is version,
+	// and returns the new version. Otherwise, it returns ErrSessionConflict.
+	// A version is not used again after the session is deleted or expired,
+	// so a version loaded before that is rejected.
+	Save(ctx context.Context, key string, data []byte, version int64) (int64, error)
+	// Delete removes the session.
+	Delete(ctx context.Context, key string) error
+}
+
+type sessionEntry struct {
+	Data    []byte    `json:"data"`
+	Version int64     `json:"version"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+func (e sessionEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && now.After(e.Expires)
+}
+
+func newSessionEntry(data []byte, version int64, ttl time.Duration) sessionEntry {
+	entry := sessionEntry{
+		Data:    data,
+		Version: version,
+	}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+
+	return entry
+}
+
+const sessionsSweepInterval = 1024
+
+// MemorySessionStore keeps sessions in memory.
+type MemorySessionStore struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	sessions   map[string]sessionEntry
+	saves      int
+	generation int64
+}
+
+// NewMemorySessionStore creates an empty MemorySessionStore. Sessions expire
+// ttl after they were last saved, or never if ttl is 0.
+func NewMemorySessionStore(ttl time.Duration) *MemorySessionStore {
+	return &MemorySessionStore{
+		ttl:      ttl,
+		sessions: make(map[string]sessionEntry),
+	}
+}
+
+// Load returns the session data and its version.
+func (s *MemorySessionStore) Load(ctx context.Context, key string) ([]byte, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[key]
+	if !ok || entry.expired(time.Now()) {
+		return nil, 0, nil
+	}
+
+	return append([]byte(nil), entry.Data...), entry.Version, nil
+}
+
+// Save stores data if the current version of the session is version.
+func (s *MemorySessionStore) Save(ctx context.Context, key string, data []byte, version int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	current := s.sessions[key]
+	if current.expired(now) {
+		current = sessionEntry{}
+	}
+	if current.Version != version {
+		return 0, ErrSessionConflict
+	}
+
+	s.generation++
+	s.sessions[key] = newSessionEntry(append([]byte(nil), data...), s.generation, s.ttl)
+
+	s.saves++
+	if s.saves%sessionsSweepInterval == 0 {
+		for k, entry := range s.sessions {
+			if entry.expired(now) {
+				delete(s.sessions, k)
+			}
+		}
+	}
+
+	return s.generation, nil
+}
+
+// Delete removes the session.
+func (s *MemorySessionStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, key)
+	return nil
+}
+
+// FileSessionStore keeps every session in its own file in a directory.
+// Versions are only checked within the process, so a directory must not be
+// shared by several processes.
+type FileSessionStore struct {
+	dir string
+	ttl time.Duration
+
+	mu         sync.Mutex
+	generation int64
+}
+
+// NewFileSessionStore creates a FileSessionStore saving to dir, creating it
+// if needed. Sessions expire ttl after they were last saved, or never if
+// ttl is 0.
+func NewFileSessionStore(dir string, ttl time.Duration) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileSessionStore{
+		dir: dir,
+		ttl: ttl,
+	}, nil
+}
+
+// maxSessionFileName limits the escaped keys used as file names, leaving
+// room for the suffixes of temporary files within NAME_MAX.
+const maxSessionFileName = 200
+
+func (s *FileSessionStore) path(key string) string {
+	name := url.QueryEscape(key)
+	if len(name) > maxSessionFileName {
+		// QueryEscape escapes "%", so the name of a hashed key differs from
+		// every escaped key.
+		sum := sha256.Sum256([]byte(key))
+		name = "%" + hex.EncodeToString(sum[:])
+	}
+
+	return filepath.Join(s.dir, name+".json")
+}
+
+// read returns the entry of key. It must be called with s.mu held.
+func (s *FileSessionStore) read(key string) (sessionEntry, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return sessionEntry{}, nil
+	}
+	if err != nil {
+		return sessionEntry{}, err
+	}
+
+	var entry sessionEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return sessionEntry{}, err
+	}
+
+	if entry.expired(time.Now()) {
+		return sessionEntry{}, nil
+	}
+
+	return entry, nil
+}
+
+// Load returns the session data and its version.
+func (s *FileSessionStore) Load(ctx context.Context, key string) ([]byte, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.read(key)
+	return entry.Data, entry.Version, err
+}
+
+// Save stores data if the current version of the session is version.
+func (s *FileSessionStore) Save(ctx context.Context, key string, data []byte, version int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.read(key)
+	if err != nil {
+		return 0, err
+	}
+	if current.Version != version {
+		return 0, ErrSessionConflict
+	}
+
+	// Versions saved before the process started are not known, so the
+	// generation also moves past the current one.
+	next := max(s.generation, current.Version) + 1
+	encoded, err := json.Marshal(newSessionEntry(data, next, s.ttl))
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeFileAtomic(s.path(key), encoded); err != nil {
+		return 0, err
+	}
+	s.generation = next
+
+	return next, nil
+}
+
+// Delete removes the session.
+func (s *FileSessionStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,150 @@
+package tgbotapi
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSession struct {
+	Language string `json:"language"`
+	Count    int    `json:"count"`
+}
+
+func testSessionStore(t *testing.T, store SessionStore) {
+	ctx := context.Background()
+
+	data, version, err := store.Load(ctx, "user:1")
+	require.NoError(t, err)
+	require.Nil(t, data)
+	require.Equal(t, int64(0), version)
+
+	version, err = store.Save(ctx, "user:1", []byte(`{"count":1}`), 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), version)
+
+	_, err = store.Save(ctx, "user:1", []byte(`{"count":2}`), 0)
+	require.ErrorIs(t, err, ErrSessionConflict)
+
+	data, version, err = store.Load(ctx, "user:1")
+	require.NoError(t, err)
+	require.Equal(t, `{"count":1}`, string(data))
+	require.Equal(t, int64(1), version)
+
+	require.NoError(t, store.Delete(ctx, "user:1"))
+	data, _, err = store.Load(ctx, "user:1")
+	require.NoError(t, err)
+	require.Nil(t, data)
+
+	// A version loaded before the session was deleted is not current again
+	// once the session is saved anew.
+	version, err = store.Save(ctx, "user:1", []byte(`{"count":3}`), 0)
+	require.NoError(t, err)
+	require.Greater(t, version, int64(1))
+	_, err = store.Save(ctx, "user:1", []byte(`{"count":4}`), 1)
+	require.ErrorIs(t, err, ErrSessionConflict)
+
+	long := strings.Repeat("é", 200)
+	version, err = store.Save(ctx, long, []byte(`{}`), 0)
+	require.NoError(t, err)
+	data, loaded, err := store.Load(ctx, long)
+	require.NoError(t, err)
+	require.Equal(t, `{}`, string(data))
+	require.Equal(t, version, loaded)
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	testSessionStore(t, NewMemorySessionStore(0))
+}
+
+func TestFileSessionStore(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	testSessionStore(t, store)
+}
+
+func TestSessionStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySessionStore(10 * time.Millisecond)
+
+	_, err := store.Save(ctx, "chat:1", []byte(`{}`), 0)
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+
+	data, version, err := store.Load(ctx, "chat:1")
+	require.NoError(t, err)
+	require.Nil(t, data)
+	require.Equal(t, int64(0), version)
+
+	_, err = store.Save(ctx, "chat:1", []byte(`{}`), 0)
+	require.NoError(t, err)
+}
+
+func TestSessionsMiddleware(t *testing.T) {
+	ctx := context.Background()
+	sessions := NewSessions[testSession](NewMemorySessionStore(0), UserSessionScope)
+
+	handler := Chain(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		session := sessions.FromContext(ctx)
+		session.Count++
+		session.Language = update.Message.Text
+		return nil
+	}), sessions.Middleware())
+
+	require.NoError(t, handler.HandleUpdate(ctx, nil, textUpdate(1, "en")))
+	require.NoError(t, handler.HandleUpdate(ctx, nil, textUpdate(1, "de")))
+
+	session, version, err := sessions.Get(ctx, "user:1")
+	require.NoError(t, err)
+	require.Equal(t, testSession{Language: "de", Count: 2}, session)
+	require.Equal(t, int64(2), version)
+}
+
+func TestSessionsMiddlewareConflict(t *testing.T) {
+	ctx := context.Background()
+	sessions := NewSessions[testSession](NewMemorySessionStore(0), UserSessionScope)
+
+	handler := Chain(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		// Another update of the same user is saved meanwhile.
+		require.NoError(t, sessions.Update(ctx, "user:1", func(s *testSession) error {
+			s.Count = 10
+			return nil
+		}))
+
+		sessions.FromContext(ctx).Language = "en"
+		return nil
+	}), sessions.Middleware())
+
+	require.ErrorIs(t, handler.HandleUpdate(ctx, nil, textUpdate(1, "hi")), ErrSessionConflict)
+
+	session, _, err := sessions.Get(ctx, "user:1")
+	require.NoError(t, err)
+	require.Equal(t, testSession{Count: 10}, session)
+}
+
+func TestSessionsUpdateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	sessions := NewSessions[testSession](NewMemorySessionStore(0), UserSessionScope)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, sessions.Update(ctx, "user:1", func(s *testSession) error {
+				s.Count++
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+
+	session, _, err := sessions.Get(ctx, "user:1")
+	require.NoError(t, err)
+	require.Equal(t, 5, session.Count)
+}
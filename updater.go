@@ -0,0 +1,218 @@
+package tgbotapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Updater receives updates with a webhook if one is configured, and by
+// polling otherwise. Either way, the updates are delivered to an
+// UpdatesChannel that is closed when the Updater stops.
+type Updater struct {
+	bot     *BotAPI
+	polling *PollingHandler
+	webhook *WebhookServer
+
+	deleteWebhook      bool
+	dropPendingUpdates bool
+	// webhookDropPending is the DropPendingUpdates of the webhook config.
+	webhookDropPending bool
+
+	mu   sync.Mutex
+	stop func(context.Context) error
+}
+
+// NewUpdater creates an Updater that polls for updates with config, until a
+// webhook is set with SetWebhook or SetWebhookURL.
+func NewUpdater(bot *BotAPI, config UpdateConfig) *Updater {
+	return &Updater{
+		bot:     bot,
+		polling: NewPollingHandler(bot, config),
+	}
+}
+
+// SetWebhook makes the Updater receive updates with a WebhookServer for
+// config.
+func (u *Updater) SetWebhook(config WebhookConfig) error {
+	server, err := NewWebhookServer(u.bot, config)
+	if err != nil {
+		return err
+	}
+
+	u.webhook = server
+	u.webhookDropPending = config.DropPendingUpdates
+	return nil
+}
+
+// SetWebhookURL makes the Updater receive updates with a WebhookServer for
+// link. If link is empty, the Updater polls for updates instead, so a
+// deployment can use a webhook while local development uses polling.
+func (u *Updater) SetWebhookURL(link string) error {
+	if link == "" {
+		u.webhook = nil
+		return nil
+	}
+
+	config, err := NewWebhook(link)
+	if err != nil {
+		return err
+	}
+
+	return u.SetWebhook(config)
+}
+
+// SetDeleteWebhook sets whether an existing webhook is deleted before
+// polling. Otherwise, polling fails if a webhook is set.
+func (u *Updater) SetDeleteWebhook(enabled bool) {
+	u.deleteWebhook = enabled
+}
+
+// SetDropPendingUpdates sets whether the updates that are waiting to be
+// received are dropped when the Updater starts. With a webhook, they are
+// also dropped if the WebhookConfig says so.
+func (u *Updater) SetDropPendingUpdates(enabled bool) {
+	u.dropPendingUpdates = enabled
+}
+
+// IsWebhook returns true if the Updater uses a webhook.
+func (u *Updater) IsWebhook() bool {
+	return u.webhook != nil
+}
+
+// PollingHandler returns the handler used for polling.
+func (u *Updater) PollingHandler() *PollingHandler {
+	return u.polling
+}
+
+// WebhookServer returns the server used for the webhook, or nil when
+// polling.
+func (u *Updater) WebhookServer() *WebhookServer {
+	return u.webhook
+}
+
+// Start starts receiving updates and returns the channel they are delivered
+// to. It runs until ctx is done or Stop is called.
+func (u *Updater) Start(ctx context.Context) (UpdatesChannel, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.stop != nil && u.running() {
+		return nil, errors.New("updater is already running")
+	}
+	u.stop = nil
+
+	if u.webhook != nil {
+		u.webhook.config.DropPendingUpdates = u.webhookDropPending || u.dropPendingUpdates
+
+		updates, err := u.webhook.Start(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		u.stop = u.webhook.Shutdown
+		return updates, nil
+	}
+
+	if err := u.takeOverPolling(ctx); err != nil {
+		return nil, err
+	}
+
+	updates, err := u.polling.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.stop = u.polling.Stop
+	return updates, nil
+}
+
+// running reports whether the webhook server or polling is running, which
+// also ends when the context passed to Start is done.
+func (u *Updater) running() bool {
+	if u.webhook != nil {
+		return u.webhook.running()
+	}
+
+	return u.polling.running()
+}
+
+// takeOverPolling deletes the webhook if needed before polling.
+func (u *Updater) takeOverPolling(ctx context.Context) error {
+	if !u.deleteWebhook && !u.dropPendingUpdates {
+		return nil
+	}
+
+	// Pending updates are dropped by deleteWebhook even if no webhook is
+	// set, so the webhook only needs to be checked otherwise.
+	if !u.dropPendingUpdates {
+		info, err := u.bot.GetWebhookInfoContext(ctx)
+		if err != nil || !info.IsSet() {
+			return err
+		}
+	}
+
+	_, err := u.bot.RequestContext(ctx, DeleteWebhookConfig{
+		DropPendingUpdates: u.dropPendingUpdates,
+	})
+	return err
+}
+
+// Stop stops receiving updates, waiting until ctx is done for the requests
+// in progress.
+func (u *Updater) Stop(ctx context.Context) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.stop == nil {
+		return nil
+	}
+
+	stop := u.stop
+	u.stop = nil
+
+	return stop(ctx)
+}
+
+// Err returns the error that stopped polling, see PollingHandler.Err.
+func (u *Updater) Err() error {
+	if u.IsWebhook() {
+		return nil
+	}
+
+	return u.polling.Err()
+}
+
+// Commit marks update as processed when polling, see PollingHandler.Commit.
+// Updates received with a webhook are confirmed by the response, so it does
+// nothing then.
+func (u *Updater) Commit(ctx context.Context, update Update) error {
+	if u.IsWebhook() {
+		return nil
+	}
+
+	return u.polling.Commit(ctx, update)
+}
+
+// Run starts receiving updates and passes every update to handler. It blocks
+// until ctx is done or Stop is called, and the updates already received have
+// been handled. If polling stopped because of a terminal error, it is
+// returned.
+//
+// Handlers are called with a context that is not canceled together with
+// ctx.
+func (u *Updater) Run(ctx context.Context, handler Handler) error {
+	updates, err := u.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	handlerCtx := context.WithoutCancel(ctx)
+	handleUpdates(handlerCtx, u.bot, handler, updates, u.Commit)
+
+	if err := u.Stop(handlerCtx); err != nil {
+		return err
+	}
+
+	return u.Err()
+}
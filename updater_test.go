@@ -0,0 +1,133 @@
+package tgbotapi
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdaterPolling(t *testing.T) {
+	server := &pollingServer{updates: []int{1}, webhookURL: "https://example.com/bot"}
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), server)
+
+	u := NewUpdater(bot, NewUpdate(0))
+	require.NoError(t, u.SetWebhookURL(""))
+	require.False(t, u.IsWebhook())
+
+	_, err := u.Start(context.Background())
+	require.Error(t, err)
+
+	u.SetDeleteWebhook(true)
+	updates, err := u.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, (<-updates).UpdateID)
+	require.Equal(t, []string{""}, server.deletes)
+
+	require.NoError(t, u.Stop(context.Background()))
+	_, ok := <-updates
+	require.False(t, ok)
+
+	u.SetDropPendingUpdates(true)
+	updates, err = u.Start(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"", "true"}, server.deletes)
+	require.NoError(t, u.Stop(context.Background()))
+}
+
+func TestUpdaterRestartAfterCancel(t *testing.T) {
+	server := &pollingServer{updates: []int{1}}
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), server)
+
+	u := NewUpdater(bot, NewUpdate(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := u.Start(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, (<-updates).UpdateID)
+
+	cancel()
+	for range updates {
+	}
+
+	updates, err = u.Start(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, u.Stop(context.Background()))
+	for range updates {
+	}
+}
+
+func TestUpdaterWebhook(t *testing.T) {
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		return newOKResponse(`{"ok": true, "result": true}`), nil
+	})
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	u := NewUpdater(bot, NewUpdate(0))
+	require.NoError(t, u.SetWebhookURL("https://127.0.0.1:8443/bot"))
+	require.True(t, u.IsWebhook())
+	u.WebhookServer().SetAddr("127.0.0.1:0")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handled := make(chan int, 1)
+	done := make(chan error)
+	go func() {
+		done <- u.Run(ctx, HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+			handled <- update.UpdateID
+			return nil
+		}))
+	}()
+
+	var addr string
+	require.Eventually(t, func() bool {
+		u.webhook.mu.Lock()
+		defer u.webhook.mu.Unlock()
+		if u.webhook.listener == nil {
+			return false
+		}
+		addr = u.webhook.listener.Addr().String()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	resp, err := httpClient.Post("https://"+addr+"/bot", "application/json", strings.NewReader(`{"update_id": 5}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Equal(t, 5, <-handled)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestUpdaterWebhookDropPendingUpdates(t *testing.T) {
+	var drop []string
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		if path.Base(req.URL.Path) == "setWebhook" {
+			require.NoError(t, req.ParseMultipartForm(1<<20))
+			drop = append(drop, req.FormValue("drop_pending_updates"))
+		}
+		return newOKResponse(`{"ok": true, "result": true}`), nil
+	})
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	config, err := NewWebhook("https://127.0.0.1:8443/bot")
+	require.NoError(t, err)
+	config.DropPendingUpdates = true
+
+	u := NewUpdater(bot, NewUpdate(0))
+	require.NoError(t, u.SetWebhook(config))
+	u.WebhookServer().SetAddr("127.0.0.1:0")
+
+	_, err = u.Start(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, u.Stop(context.Background()))
+	require.Equal(t, []string{"true"}, drop)
+}
@@ -0,0 +1,366 @@
+package tgbotapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// WebhookPorts are the ports Telegram can send webhook requests to.
+var WebhookPorts = []string{"443", "80", "88", "8443"}
+
+// WebhookServer serves a webhook over HTTPS, and registers it with Telegram
+// when started.
+//
+// Unless a certificate is set with SetCertificateFiles, a self-signed
+// certificate is generated for the host of the webhook URL, and uploaded
+// with setWebhook.
+type WebhookServer struct {
+	bot     *BotAPI
+	handler *WebhookHandler
+	config  WebhookConfig
+
+	addr             string
+	certFile         string
+	keyFile          string
+	deleteOnShutdown bool
+
+	mu         sync.Mutex
+	server     *http.Server
+	listener   net.Listener
+	stopped    chan struct{}
+	abort      chan struct{}
+	forwarded  chan struct{}
+	stopOnDone func() bool
+}
+
+// NewWebhookServer creates a WebhookServer for config. The URL must use
+// HTTPS and one of WebhookPorts. If config has a SecretToken, the handler
+// verifies it.
+func NewWebhookServer(bot *BotAPI, config WebhookConfig) (*WebhookServer, error) {
+	if config.URL == nil || config.URL.Scheme != "https" {
+		return nil, errors.New("webhook URL must use https")
+	}
+
+	port := config.URL.Port()
+	if port == "" {
+		port = "443"
+	}
+
+	allowed := false
+	for _, p := range WebhookPorts {
+		allowed = allowed || p == port
+	}
+	if !allowed {
+		return nil, fmt.Errorf("webhook port %s is not supported by Telegram", port)
+	}
+
+	handler := NewWebhookHandler(bot)
+	handler.SetSecretToken(config.SecretToken)
+
+	return &WebhookServer{
+		bot:     bot,
+		handler: handler,
+		config:  config,
+		addr:    ":" + port,
+	}, nil
+}
+
+// Handler returns the WebhookHandler serving the webhook, to configure it
+// before the server is started.
+func (s *WebhookServer) Handler() *WebhookHandler {
+	return s.handler
+}
+
+// SetAddr sets the address to listen on. It defaults to all interfaces on
+// the port of the webhook URL.
+func (s *WebhookServer) SetAddr(addr string) {
+	s.addr = addr
+}
+
+// SetCertificateFiles sets the certificate and key to serve, instead of
+// generating a self-signed certificate. The certificate is only uploaded if
+// it is set as the Certificate of the WebhookConfig.
+func (s *WebhookServer) SetCertificateFiles(certFile, keyFile string) {
+	s.certFile = certFile
+	s.keyFile = keyFile
+}
+
+// SetDeleteWebhookOnShutdown sets whether Shutdown calls deleteWebhook.
+func (s *WebhookServer) SetDeleteWebhookOnShutdown(enabled bool) {
+	s.deleteOnShutdown = enabled
+}
+
+// Start listens for webhook requests, calls setWebhook, and returns the
+// channel the received updates are delivered to. The server runs until ctx
+// is done or Shutdown is called, then the channel is closed once the
+// received updates have been read from it. When ctx is done, updates that
+// are not read within 10 seconds are dropped.
+func (s *WebhookServer) Start(ctx context.Context) (UpdatesChannel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		return nil, errors.New("webhook server is already running")
+	}
+
+	config := s.config
+	cert, err := s.certificate(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.bot.RequestContext(ctx, config); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	path := config.URL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, s.handler)
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		},
+	}
+
+	go func() {
+		if err := server.ServeTLS(listener, "", ""); !errors.Is(err, http.ErrServerClosed) {
+			log.Println("Webhook server failed:", err)
+		}
+	}()
+
+	updates := make(chan Update)
+	stopped := make(chan struct{})
+	abort := make(chan struct{})
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		forwardUpdates(s.handler.Updates(), updates, stopped, abort)
+	}()
+
+	s.server = server
+	s.listener = listener
+	s.stopped = stopped
+	s.abort = abort
+	s.forwarded = forwarded
+	s.stopOnDone = context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// The server may have been shut down and started again since.
+		if s.server != server {
+			return
+		}
+
+		if err := s.shutdown(shutdownCtx); err != nil {
+			log.Println("Failed to shut down webhook server:", err)
+		}
+	})
+
+	return updates, nil
+}
+
+// running reports whether the server is running. It is shut down when the
+// context passed to Start is done.
+func (s *WebhookServer) running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.server != nil
+}
+
+// forwardUpdates passes updates from in to out until stopped is closed and
+// in is empty, or abort is closed, then closes out.
+func forwardUpdates(in UpdatesChannel, out chan<- Update, stopped, abort <-chan struct{}) {
+	defer close(out)
+
+	send := func(update Update) bool {
+		select {
+		case out <- update:
+			return true
+		case <-abort:
+			log.Printf("Dropped update %d, it was not read before shutdown\n", update.UpdateID)
+			return false
+		}
+	}
+
+	for {
+		select {
+		case update := <-in:
+			if !send(update) {
+				return
+			}
+		case <-stopped:
+			for {
+				select {
+				case update := <-in:
+					if !send(update) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		case <-abort:
+			return
+		}
+	}
+}
+
+// certificate returns the certificate to serve. A generated certificate is
+// set as the Certificate of config.
+func (s *WebhookServer) certificate(config *WebhookConfig) (tls.Certificate, error) {
+	if s.certFile != "" {
+		return tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	}
+
+	certPEM, keyPEM, err := GenerateSelfSignedCertificate(config.URL.Hostname(), 10*365*24*time.Hour)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	config.Certificate = FileBytes{Name: "cert.pem", Bytes: certPEM}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// Shutdown stops the server, waiting until ctx is done for the requests in
+// progress and for the received updates to be read from the channel.
+// Updates that were not read by then are dropped. If enabled, the webhook is
+// deleted afterwards.
+func (s *WebhookServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.shutdown(ctx)
+}
+
+// shutdown stops the server. It must be called with s.mu held.
+func (s *WebhookServer) shutdown(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
+
+	s.stopOnDone()
+
+	// Requests in progress may be waiting for room in the updates channel,
+	// which is emptied by forwardUpdates meanwhile.
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		// Closing the connections cancels the requests still waiting.
+		s.server.Close()
+	}
+	close(s.stopped)
+
+	select {
+	case <-s.forwarded:
+	case <-ctx.Done():
+		close(s.abort)
+		<-s.forwarded
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+
+	s.server = nil
+	s.listener = nil
+	s.stopped = nil
+	s.abort = nil
+	s.forwarded = nil
+
+	if s.deleteOnShutdown {
+		if _, deleteErr := s.bot.RequestContext(ctx, DeleteWebhookConfig{}); deleteErr != nil {
+			err = errors.Join(err, deleteErr)
+		}
+	}
+
+	return err
+}
+
+// Run starts the server and passes every update to handler. It blocks until
+// ctx is done, the server is shut down and the updates already received have
+// been handled.
+//
+// Handlers are called with a context that is not canceled together with
+// ctx.
+func (s *WebhookServer) Run(ctx context.Context, handler Handler) error {
+	updates, err := s.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	handleUpdates(context.WithoutCancel(ctx), s.bot, handler, updates, nil)
+
+	return nil
+}
+
+// GenerateSelfSignedCertificate generates a PEM encoded self-signed
+// certificate and private key for host, which is an IP address or a domain
+// name, valid for the given duration.
+func GenerateSelfSignedCertificate(host string, validFor time.Duration) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: host},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		template.IPAddresses = []net.IP{addr.AsSlice()}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,157 @@
+package tgbotapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebhookServer(t *testing.T) {
+	bot := NewBot(NewDefaultBotConfig(TestToken))
+
+	config, err := NewWebhook("http://example.com/bot")
+	require.NoError(t, err)
+	_, err = NewWebhookServer(bot, config)
+	require.Error(t, err)
+
+	config, err = NewWebhook("https://example.com:8080/bot")
+	require.NoError(t, err)
+	_, err = NewWebhookServer(bot, config)
+	require.Error(t, err)
+
+	config, err = NewWebhook("https://example.com/bot")
+	require.NoError(t, err)
+	s, err := NewWebhookServer(bot, config)
+	require.NoError(t, err)
+	require.Equal(t, ":443", s.addr)
+}
+
+func TestGenerateSelfSignedCertificate(t *testing.T) {
+	for _, host := range []string{"203.0.113.10", "bot.example.com"} {
+		certPEM, keyPEM, err := GenerateSelfSignedCertificate(host, time.Hour)
+		require.NoError(t, err)
+
+		_, err = tls.X509KeyPair(certPEM, keyPEM)
+		require.NoError(t, err)
+
+		block, _ := pem.Decode(certPEM)
+		cert, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		require.NoError(t, cert.VerifyHostname(host))
+	}
+}
+
+func TestWebhookServer(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		methods = append(methods, path.Base(req.URL.Path))
+		mu.Unlock()
+
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+			require.NoError(t, req.ParseMultipartForm(1<<20))
+			_, _, err := req.FormFile("certificate")
+			require.NoError(t, err)
+		}
+
+		return newErrorResponse(http.StatusOK, `{"ok": true, "result": true}`), nil
+	})
+
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	config, err := NewWebhook("https://127.0.0.1:8443/bot")
+	require.NoError(t, err)
+	config.SecretToken = "secret"
+
+	s, err := NewWebhookServer(bot, config)
+	require.NoError(t, err)
+	s.SetAddr("127.0.0.1:0")
+	s.SetDeleteWebhookOnShutdown(true)
+
+	updates, err := s.Start(context.Background())
+	require.NoError(t, err)
+
+	httpClient := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+
+	req, err := http.NewRequest(http.MethodPost, "https://"+s.listener.Addr().String()+"/bot", strings.NewReader(`{"update_id": 7}`))
+	require.NoError(t, err)
+	req.Header.Set(SecretTokenHeader, "secret")
+
+	resp, err := httpClient.Do(req)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 7, (<-updates).UpdateID)
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.Equal(t, []string{"setWebhook", "deleteWebhook"}, methods)
+}
+
+func startTestWebhookServer(t *testing.T, ctx context.Context) (*WebhookServer, UpdatesChannel) {
+	client := httpClientFunc(func(req *http.Request) (*http.Response, error) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		return newOKResponse(`{"ok": true, "result": true}`), nil
+	})
+	bot := NewBotWithClient(NewBotConfig(TestToken, APIEndpoint, false), client)
+
+	config, err := NewWebhook("https://127.0.0.1:8443/bot")
+	require.NoError(t, err)
+
+	s, err := NewWebhookServer(bot, config)
+	require.NoError(t, err)
+	s.SetAddr("127.0.0.1:0")
+
+	updates, err := s.Start(ctx)
+	require.NoError(t, err)
+
+	return s, updates
+}
+
+func TestWebhookServerShutdownWithPendingUpdate(t *testing.T) {
+	s, updates := startTestWebhookServer(t, context.Background())
+
+	require.Equal(t, http.StatusOK, serveWebhook(s.Handler(), 1))
+
+	// Nobody reads the update, so Shutdown gives up when ctx is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, s.Shutdown(ctx), context.DeadlineExceeded)
+
+	_, ok := <-updates
+	require.False(t, ok)
+}
+
+func TestWebhookServerRestart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, _ := startTestWebhookServer(t, ctx)
+	require.NoError(t, s.Shutdown(context.Background()))
+
+	updates, err := s.Start(context.Background())
+	require.NoError(t, err)
+
+	// The context of the first run no longer affects the server.
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	require.True(t, s.running())
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	_, ok := <-updates
+	require.False(t, ok)
+}
@@ -0,0 +1,225 @@
+package tgbotapi
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// WorkerPoolConfig configures a WorkerPool.
+type WorkerPoolConfig struct {
+	// Workers is the number of updates processed concurrently.
+	// Defaults to GOMAXPROCS.
+	Workers int
+	// QueueSize is the number of updates the pool holds per worker. When
+	// Workers*QueueSize updates are queued or being processed, the pool
+	// stops reading updates until one is done. Defaults to 16.
+	QueueSize int
+	// DrainTimeout limits how long queued updates are still processed after
+	// the pool is stopped. When it expires, the context passed to handlers is
+	// canceled. Zero waits for all queued updates.
+	DrainTimeout time.Duration
+}
+
+// WorkerPool processes updates concurrently with a fixed number of workers.
+//
+// Every chat has its own queue, which is processed by one worker at a time,
+// so updates from the same chat are handled one at a time and in order,
+// while different chats are handled in parallel by free workers.
+type WorkerPool struct {
+	handler Handler
+	config  WorkerPoolConfig
+
+	mu      sync.RWMutex
+	onError ErrorHandlerFunc
+}
+
+// NewWorkerPool creates a WorkerPool that passes updates to handler.
+func NewWorkerPool(handler Handler, config WorkerPoolConfig) *WorkerPool {
+	if config.Workers <= 0 {
+		config.Workers = runtime.GOMAXPROCS(0)
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 16
+	}
+
+	return &WorkerPool{
+		handler: handler,
+		config:  config,
+	}
+}
+
+// OnError sets the function called with errors returned by the handler.
+// By default, errors are logged.
+func (p *WorkerPool) OnError(fn ErrorHandlerFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.onError = fn
+}
+
+// chatQueues holds the queued updates of every chat with updates queued or
+// being processed. Chats are removed once they have none left.
+type chatQueues struct {
+	// slots limits the number of updates queued or being processed.
+	slots chan struct{}
+	// ready receives the key of a chat when its updates need a worker.
+	ready chan uint64
+
+	mu    sync.Mutex
+	chats map[uint64][]Update
+}
+
+func newChatQueues(limit int) *chatQueues {
+	return &chatQueues{
+		slots: make(chan struct{}, limit),
+		// Every chat waiting for a worker holds a slot, so this never
+		// blocks.
+		ready: make(chan uint64, limit),
+		chats: make(map[uint64][]Update),
+	}
+}
+
+// push queues update to the queue of its chat, waiting for a free slot
+// until ctx is done.
+func (q *chatQueues) push(ctx context.Context, update Update) error {
+	select {
+	case q.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	key := updateKey(update)
+
+	q.mu.Lock()
+	pending, active := q.chats[key]
+	q.chats[key] = append(pending, update)
+	q.mu.Unlock()
+
+	if !active {
+		q.ready <- key
+	}
+
+	return nil
+}
+
+// next removes the next update of the chat, or returns false and removes
+// the chat when it has none left.
+func (q *chatQueues) next(key uint64) (Update, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending := q.chats[key]
+	if len(pending) == 0 {
+		delete(q.chats, key)
+		return Update{}, false
+	}
+
+	q.chats[key] = pending[1:]
+	return pending[0], true
+}
+
+// done frees the slot of a processed update.
+func (q *chatQueues) done() {
+	<-q.slots
+}
+
+// Serve processes updates from the channel until it is closed or ctx is done.
+// Updates that were already queued are processed before Serve returns.
+func (p *WorkerPool) Serve(ctx context.Context, bot *BotAPI, updates UpdatesChannel) error {
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	queues := newChatQueues(p.config.Workers * p.config.QueueSize)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for key := range queues.ready {
+				for update, ok := queues.next(key); ok; update, ok = queues.next(key) {
+					if err := handleUpdate(workCtx, p.handler, bot, update); err != nil {
+						p.handleError(workCtx, update, err)
+					}
+					queues.done()
+				}
+			}
+		}()
+	}
+
+	err := p.distribute(ctx, updates, queues)
+
+	// Only distribute marks chats as ready, so the workers finish the chats
+	// that are left and stop.
+	close(queues.ready)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	if p.config.DrainTimeout > 0 {
+		timer := time.NewTimer(p.config.DrainTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+			cancelWork()
+			<-done
+		}
+	} else {
+		<-done
+	}
+
+	return err
+}
+
+// distribute reads updates and queues them to the queue of their chat.
+func (p *WorkerPool) distribute(ctx context.Context, updates UpdatesChannel, queues *chatQueues) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+
+			if err := queues.push(ctx, update); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// updateKey returns the key deciding which queue holds an update: the chat,
+// the sender, or the update itself.
+func updateKey(update Update) uint64 {
+	if chat := update.FromChat(); chat != nil {
+		return uint64(chat.ID)
+	}
+
+	if user := update.SentFrom(); user != nil {
+		return uint64(user.ID)
+	}
+
+	return uint64(update.UpdateID)
+}
+
+func (p *WorkerPool) handleError(ctx context.Context, update Update, err error) {
+	p.mu.RLock()
+	onError := p.onError
+	p.mu.RUnlock()
+
+	if onError != nil {
+		onError(ctx, update, err)
+		return
+	}
+
+	log.Printf("Failed to handle update %d: %v\n", update.UpdateID, err)
+}
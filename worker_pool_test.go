@@ -0,0 +1,110 @@
+package tgbotapi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPoolOrderPerChat(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		handled = make(map[int64][]int)
+	)
+
+	pool := NewWorkerPool(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		chatID := update.FromChat().ID
+		handled[chatID] = append(handled[chatID], update.UpdateID)
+		return nil
+	}), WorkerPoolConfig{Workers: 4, QueueSize: 2})
+
+	ch := make(chan Update)
+	go func() {
+		for i := 0; i < 40; i++ {
+			update := textUpdate(int64(i%5), "")
+			update.UpdateID = i
+			ch <- update
+		}
+		close(ch)
+	}()
+
+	require.NoError(t, pool.Serve(context.Background(), nil, ch))
+
+	for chatID := int64(0); chatID < 5; chatID++ {
+		ids := handled[chatID]
+		require.Len(t, ids, 8)
+		for i := 1; i < len(ids); i++ {
+			require.Less(t, ids[i-1], ids[i])
+		}
+	}
+}
+
+func TestWorkerPoolSlowChat(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan int64, 10)
+
+	pool := NewWorkerPool(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		chatID := update.FromChat().ID
+		if chatID == 1 {
+			<-release
+		}
+		handled <- chatID
+		return nil
+	}), WorkerPoolConfig{Workers: 2, QueueSize: 4})
+
+	ch := make(chan Update)
+	done := make(chan error)
+	go func() {
+		done <- pool.Serve(context.Background(), nil, ch)
+	}()
+
+	// Chats sharing a worker with the blocked chat still make progress.
+	for i := 0; i < 4; i++ {
+		ch <- textUpdate(1, "")
+	}
+	for chatID := int64(2); chatID < 5; chatID++ {
+		ch <- textUpdate(chatID, "")
+		require.Equal(t, chatID, <-handled)
+	}
+
+	close(release)
+	close(ch)
+	require.NoError(t, <-done)
+	require.Len(t, handled, 4)
+}
+
+func TestWorkerPoolDrain(t *testing.T) {
+	release := make(chan struct{})
+	var handled int
+
+	pool := NewWorkerPool(HandlerFunc(func(ctx context.Context, bot *BotAPI, update Update) error {
+		<-release
+		handled++
+		return nil
+	}), WorkerPoolConfig{Workers: 1, QueueSize: 4})
+
+	ch := make(chan Update, 3)
+	for i := 0; i < 3; i++ {
+		ch <- textUpdate(1, "")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- pool.Serve(ctx, nil, ch)
+	}()
+
+	require.Eventually(t, func() bool { return len(ch) == 0 }, time.Second, time.Millisecond)
+	cancel()
+	close(release)
+
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.Equal(t, 3, handled)
+}